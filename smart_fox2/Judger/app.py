@@ -23,6 +23,17 @@ STATUS_MEMORY_LIMIT_EXCEEDED = "Memory Limit Exceeded"
 STATUS_RUNTIME_ERROR = "Runtime Error"
 STATUS_COMPILE_ERROR = "Compilation Error"
 STATUS_INTERNAL_ERROR = "Internal Error"
+STATUS_CHECKER_ERROR = "Checker Error"
+
+# --- 输出比较方式 ---
+COMPARE_EXACT = "exact"  # 逐行比较，忽略行尾空白和末尾空行
+COMPARE_IGNORE_WHITESPACE = "ignore_whitespace"  # 按空白分词后比较
+COMPARE_FLOAT = "float"  # 按空白分词，数值在误差范围内视为相同
+COMPARE_CHECKER = "checker"  # 由教师提供的检查程序判定
+COMPARE_MODES = [COMPARE_EXACT, COMPARE_IGNORE_WHITESPACE, COMPARE_FLOAT, COMPARE_CHECKER]
+DEFAULT_FLOAT_EPSILON = 1e-6
+CHECKER_TIME_LIMIT = 5  # seconds
+CHECKER_MESSAGE_LIMIT = 1000  # 检查程序输出信息的最大长度
 
 
 if os.name == 'nt':  # Windows
@@ -69,7 +80,12 @@ def set_resource_limits_unix(time_limit_sec, memory_limit_bytes):
             print(f"Warning (in preexec_fn): Could not set resource limits: {e}")
 
 
-def compare_outputs(actual_output, expected_output):
+def compare_outputs(actual_output, expected_output, compare_mode=COMPARE_EXACT, float_epsilon=DEFAULT_FLOAT_EPSILON):
+    if compare_mode == COMPARE_IGNORE_WHITESPACE:
+        return actual_output.split() == expected_output.split()
+    if compare_mode == COMPARE_FLOAT:
+        return compare_float_tokens(actual_output, expected_output, float_epsilon)
+
     actual_lines = [line.rstrip() for line in actual_output.strip().splitlines()] # 实际输出
     expected_lines = [line.rstrip() for line in expected_output.strip().splitlines()] # 期望输出
 
@@ -81,10 +97,113 @@ def compare_outputs(actual_output, expected_output):
     return actual_lines == expected_lines
 
 
+def compare_float_tokens(actual_output, expected_output, float_epsilon):
+    # 数值按绝对误差或相对误差比较，其余内容需完全一致
+    actual_tokens = actual_output.split()
+    expected_tokens = expected_output.split()
+    if len(actual_tokens) != len(expected_tokens):
+        return False
+    for actual, expected in zip(actual_tokens, expected_tokens):
+        try:
+            actual_value, expected_value = float(actual), float(expected)
+        except ValueError:
+            if actual != expected:
+                return False
+            continue
+        diff = abs(actual_value - expected_value)
+        if not diff <= float_epsilon and not diff <= float_epsilon * abs(expected_value):
+            return False
+    return True
+
+
+def prepare_checker(checker):
+    # 编译检查程序，返回运行命令、检查程序目录和错误信息
+    # 检查程序和用例数据放在学生程序工作目录之外的单独临时目录中，避免学生程序读取隐藏用例的期望输出
+    language = checker.get("language", "")
+    config = LANGUAGE_CONFIG.get(language)
+    if config is None:
+        return None, None, f"不支持的检查程序语言：'{language}'"
+
+    checker_dir = tempfile.mkdtemp(prefix="checker_")
+    cmd, error = compile_checker(checker, config, language, checker_dir)
+    if cmd is None:
+        shutil.rmtree(checker_dir, ignore_errors=True)
+        return None, None, error
+    return cmd, checker_dir, None
+
+
+def compile_checker(checker, config, language, checker_dir):
+    # 在检查程序目录中写入源码并编译，返回运行命令和错误信息
+    src_file_name = "Main.java" if language == "java" else f"checker.{language}"
+    src_file_path = os.path.join(checker_dir, src_file_name)
+    exe_file_path_base = os.path.join(checker_dir, "checker")
+    with open(src_file_path, "w", encoding="utf-8") as f:
+        f.write(checker.get("source_code", ""))
+
+    if config["compile_cmd"]:
+        compile_cmd_str = config["compile_cmd"].format(src_file=src_file_path, exe_file_base=exe_file_path_base)
+        try:
+            compile_process = subprocess.run(
+                compile_cmd_str.split(),
+                capture_output=True, text=True, timeout=30,
+                cwd=checker_dir
+            )
+            if compile_process.returncode != 0:
+                return None, "Checker compilation failed: " + compile_process.stderr[:CHECKER_MESSAGE_LIMIT]
+        except subprocess.TimeoutExpired:
+            return None, "Checker compilation timed out."
+        except Exception as e:
+            return None, f"Unexpected checker compilation error: {e}"
+
+    run_cmd_str = config["run_cmd"].format(
+        src_file=src_file_path,
+        exe_file_base=exe_file_path_base,
+        class_name="Main"
+    )
+    return run_cmd_str.split(), None
+
+
+def run_checker(checker_cmd, checker_dir, test_input, actual_output, expected_output):
+    # 按 testlib 约定调用检查程序：checker <input> <output> <answer>
+    # 退出码 0 为通过，1 为答案错误，其他为检查程序错误；提示信息取自 stderr，为空时取 stdout
+    # 每个用例的数据文件写入单独的目录，判定后立即删除
+    case_dir = tempfile.mkdtemp(prefix="case_", dir=checker_dir)
+    try:
+        files = []
+        for name, content in (("input.txt", test_input), ("output.txt", actual_output), ("answer.txt", expected_output)):
+            path = os.path.join(case_dir, name)
+            with open(path, "w", encoding="utf-8") as f:
+                f.write(content)
+            files.append(path)
+
+        process = subprocess.run(
+            checker_cmd + files,
+            capture_output=True, text=True,
+            timeout=CHECKER_TIME_LIMIT,
+            cwd=checker_dir
+        )
+    except subprocess.TimeoutExpired:
+        return STATUS_CHECKER_ERROR, "Checker timed out."
+    except Exception as e:
+        return STATUS_CHECKER_ERROR, str(e)
+    finally:
+        shutil.rmtree(case_dir, ignore_errors=True)
+
+    message = (process.stderr.strip() or process.stdout.strip())[:CHECKER_MESSAGE_LIMIT]
+    if process.returncode == 0:
+        return STATUS_ACCEPTED, message
+    if process.returncode == 1:
+        return STATUS_WRONG_ANSWER, message
+    return STATUS_CHECKER_ERROR, message or f"Checker exited with code {process.returncode}"
+
+
 
 def evaluate_code(language, source_code, test_cases,
                   time_limit_sec=DEFAULT_TIME_LIMIT,
-                  memory_limit_bytes=DEFAULT_MEMORY_LIMIT_BYTES):
+                  memory_limit_bytes=DEFAULT_MEMORY_LIMIT_BYTES,
+                  compare_mode=COMPARE_EXACT,
+                  float_epsilon=DEFAULT_FLOAT_EPSILON,
+                  checker=None):
 
     num_total_cases = len(test_cases)
 
@@ -166,6 +285,19 @@ def evaluate_code(language, source_code, test_cases,
         }
         return {"case_results": case_results, "summary": summary}
 
+    # 编译检查程序
+    checker_cmd, checker_dir = None, None
+    if compare_mode == COMPARE_CHECKER:
+        checker_cmd, checker_dir, checker_error = prepare_checker(checker or {})
+        if checker_cmd is None:
+            shutil.rmtree(temp_dir)
+            case_results = [{"status": STATUS_CHECKER_ERROR, "details": checker_error}] * num_total_cases
+            summary = {
+                "total_cases": num_total_cases, "passed_cases": 0, "pass_rate_percent": 0.0,
+                "overall_status": STATUS_CHECKER_ERROR
+            }
+            return {"case_results": case_results, "summary": summary}
+
     # --- 对每一个评测点进行评测 ---
     first_error_status = None
 
@@ -219,7 +351,11 @@ def evaluate_code(language, source_code, test_cases,
                     case_result["status"] = STATUS_MEMORY_LIMIT_EXCEEDED
                 else:
                     case_result["status"] = STATUS_RUNTIME_ERROR
-            elif compare_outputs(actual_output, expected_output):
+            elif checker_cmd is not None:
+                status, message = run_checker(checker_cmd, checker_dir, test_input, actual_output, expected_output)
+                case_result["status"] = status
+                case_result["checker_message"] = message
+            elif compare_outputs(actual_output, expected_output, compare_mode, float_epsilon):
                 case_result["status"] = STATUS_ACCEPTED
             else:
                 case_result["status"] = STATUS_WRONG_ANSWER
@@ -254,6 +390,8 @@ def evaluate_code(language, source_code, test_cases,
         shutil.rmtree(temp_dir)
     except Exception as e:
         print(f"Warning: Could not remove temporary directory {temp_dir}: {e}")
+    if checker_dir is not None:
+        shutil.rmtree(checker_dir, ignore_errors=True)
 
     # 计算通过率和总体状态
     passed_count = 0
@@ -317,6 +455,16 @@ def handle_evaluate():
     memory_limit_mb = data.get("memory_limit_mb", DEFAULT_MEMORY_LIMIT_BYTES / (1024 * 1024))  # MB
     memory_limit_bytes = int(memory_limit_mb * 1024 * 1024)  # 转换为字节
 
+    # 获取可选的输出比较方式和检查程序
+    compare_mode = data.get("compare_mode") or COMPARE_EXACT
+    if compare_mode not in COMPARE_MODES:
+        return jsonify({"error": f"不支持的比较方式: {compare_mode}"}), 400
+    float_epsilon = data.get("float_epsilon") or DEFAULT_FLOAT_EPSILON
+    checker = data.get("checker")
+    if compare_mode == COMPARE_CHECKER and (
+            not isinstance(checker, dict) or not checker.get("source_code") or checker.get("language") not in LANGUAGE_CONFIG):
+        return jsonify({"error": "checker 比较方式需要提供 language 和 source_code"}), 400
+
     # 日志记录请求信息
     print(f"收到评测信息: {language}, {len(test_cases)} test cases.")
     print(f"评测代码:\n{source_code[:1000]}")
@@ -328,7 +476,10 @@ def handle_evaluate():
             source_code=source_code,
             test_cases=test_cases,
             time_limit_sec=time_limit,
-            memory_limit_bytes=memory_limit_bytes
+            memory_limit_bytes=memory_limit_bytes,
+            compare_mode=compare_mode,
+            float_epsilon=float_epsilon,
+            checker=checker
         )
         return jsonify(evaluation_response), 200
     except Exception as e:
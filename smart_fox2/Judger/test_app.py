@@ -12,7 +12,7 @@ from unittest.mock import patch, MagicMock
 sys.path.append(os.path.dirname(os.path.abspath(__file__)))
 
 # 导入被测试的模块
-from app import evaluate_code, compare_outputs, STATUS_ACCEPTED, STATUS_WRONG_ANSWER
+from app import evaluate_code, compare_outputs, STATUS_ACCEPTED, STATUS_WRONG_ANSWER, STATUS_CHECKER_ERROR
 
 class TestJudger(unittest.TestCase):
     def setUp(self):
@@ -61,6 +61,78 @@ class TestJudger(unittest.TestCase):
         self.assertFalse(compare_outputs("  hello  \n  world  ", "hello\nworld"))
         self.assertFalse(compare_outputs("hello", "world"))
         self.assertFalse(compare_outputs("hello\nworld", "hello\nearth"))
+
+    def test_compare_outputs_modes(self):
+        # 测试忽略空白和浮点误差的比较方式
+        self.assertTrue(compare_outputs("  hello  \n  world  ", "hello world", "ignore_whitespace"))
+        self.assertFalse(compare_outputs("helloworld", "hello world", "ignore_whitespace"))
+        self.assertTrue(compare_outputs("0.3333333 x\n", "0.33333333 x", "float", 1e-6))
+        self.assertTrue(compare_outputs("100000.1", "100000.0", "float", 1e-5))
+        self.assertFalse(compare_outputs("0.33", "0.3333", "float", 1e-6))
+        self.assertFalse(compare_outputs("0.3333 y", "0.3333 x", "float", 1e-6))
+        self.assertFalse(compare_outputs("1 2", "1", "float", 1e-6))
+
+    def test_checker_evaluation(self):
+        # 测试检查程序判定多解题目：输出任意一个拓扑序即可
+        checker_code = """
+import sys
+edges = [tuple(map(int, line.split())) for line in open(sys.argv[1]).read().splitlines()]
+order = open(sys.argv[2]).read().split()
+pos = {v: i for i, v in enumerate(map(int, order))}
+for u, v in edges:
+    if pos[u] > pos[v]:
+        print(f"edge {u}->{v} violated", file=sys.stderr)
+        sys.exit(1)
+print("ok", file=sys.stderr)
+"""
+        python_code = "print(input())"
+        test_cases = [
+            {"input": "1 2", "expected_output": "1 2"},
+            {"input": "2 1", "expected_output": "2 1"},
+        ]
+        checker = {"language": "python", "source_code": checker_code}
+
+        # 第二个用例原样输出 "2 1"，满足 2->1 的边
+        result = evaluate_code("python", python_code, test_cases, compare_mode="checker", checker=checker)
+        self.assertEqual(result["summary"]["overall_status"], STATUS_ACCEPTED)
+        self.assertEqual(result["case_results"][0]["checker_message"], "ok")
+
+        result = evaluate_code("python", "print('2 1')", test_cases[:1], compare_mode="checker", checker=checker)
+        self.assertEqual(result["summary"]["overall_status"], STATUS_WRONG_ANSWER)
+        self.assertEqual(result["case_results"][0]["checker_message"], "edge 1->2 violated")
+
+    def test_checker_files_hidden_from_submission(self):
+        # 检查程序和用例数据不在学生程序可见的工作目录中，评测结束后全部删除
+        checker_code = """
+import sys
+sys.exit(0 if open(sys.argv[2]).read().strip() == "safe" else 1)
+"""
+        python_code = """
+import os
+found = [f for d in (".", "..") for _, _, files in os.walk(d) for f in files if f in ("answer.txt", "input.txt") or f.startswith("checker")]
+print("leak" if found else "safe")
+"""
+        test_cases = [
+            {"input": "", "expected_output": "secret-1"},
+            {"input": "", "expected_output": "secret-2"},
+        ]
+        checker = {"language": "python", "source_code": checker_code}
+        before = set(glob.glob(os.path.join(tempfile.gettempdir(), "checker_*")))
+
+        result = evaluate_code("python", python_code, test_cases, compare_mode="checker", checker=checker)
+        self.assertEqual(result["summary"]["overall_status"], STATUS_ACCEPTED)
+        self.assertEqual(set(glob.glob(os.path.join(tempfile.gettempdir(), "checker_*"))), before)
+
+    def test_checker_error(self):
+        # 测试检查程序本身出错
+        checker = {"language": "python", "source_code": "import sys\nsys.exit(3)"}
+        test_cases = [{"input": "", "expected_output": ""}]
+
+        result = evaluate_code("python", "print(1)", test_cases, compare_mode="checker", checker=checker)
+        self.assertEqual(result["summary"]["overall_status"], STATUS_CHECKER_ERROR)
+
+        result = evaluate_code("python", "print(1)", test_cases, compare_mode="checker", checker={"language": "ruby"})
+        self.assertEqual(result["summary"]["overall_status"], STATUS_CHECKER_ERROR)
     
     def test_python_evaluation(self):
         # 测试 Python 代码评测
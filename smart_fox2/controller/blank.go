@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lh/models"
+	"regexp"
+	"strings"
+)
+
+// BlankConfig 填空题判分配置，一道题可包含多个空，题干中用 {{1}}、{{2}} 标出各空位置
+type BlankConfig struct {
+	Blanks        []BlankSpec `json:"blanks"`
+	IgnoreCase    bool        `json:"ignore_case"`    // 忽略大小写
+	CollapseSpace bool        `json:"collapse_space"` // 合并连续空白，忽略首尾空白
+	FullWidth     bool        `json:"full_width"`     // 全角字符按半角处理
+}
+
+// BlankSpec 单个空的可接受答案，Answers 和 Regex 任一匹配即判为正确
+type BlankSpec struct {
+	Answers []string `json:"answers"`
+	Regex   string   `json:"regex,omitempty"` // 需整体匹配
+	Score   int      `json:"score,omitempty"` // 该空分值，各空都不填时平分题目分值
+}
+
+var blankPlaceholder = regexp.MustCompile(`\{\{\s*(\d+)\s*\}\}`)
+
+// normalizeBlankAnswer 按配置规范化填空答案
+func normalizeBlankAnswer(answer string, config BlankConfig) string {
+	if config.FullWidth {
+		answer = strings.Map(func(r rune) rune {
+			switch {
+			case r == '　':
+				return ' '
+			case r >= '！' && r <= '～':
+				return r - 0xFEE0
+			}
+			return r
+		}, answer)
+	}
+	if config.CollapseSpace {
+		answer = strings.Join(strings.Fields(answer), " ")
+	}
+	if config.IgnoreCase {
+		answer = strings.ToLower(answer)
+	}
+	return answer
+}
+
+// blankRegexp 编译某个空的正则，要求整体匹配
+func blankRegexp(spec BlankSpec, config BlankConfig) (*regexp.Regexp, error) {
+	pattern := "^(?:" + spec.Regex + ")$"
+	if config.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// blankMatches 判断学生在某个空的作答是否正确
+func blankMatches(answer string, spec BlankSpec, config BlankConfig) bool {
+	answer = normalizeBlankAnswer(answer, config)
+	for _, accepted := range spec.Answers {
+		if answer == normalizeBlankAnswer(accepted, config) {
+			return true
+		}
+	}
+	if spec.Regex != "" {
+		re, err := blankRegexp(spec, config)
+		return err == nil && re.MatchString(answer)
+	}
+	return false
+}
+
+// parseBlankConfig 读取题目的填空配置，未配置时返回 false
+func parseBlankConfig(question models.Question) (BlankConfig, bool) {
+	var config BlankConfig
+	if question.BlankConfig == "" {
+		return config, false
+	}
+	if err := json.Unmarshal([]byte(question.BlankConfig), &config); err != nil || len(config.Blanks) == 0 {
+		return config, false
+	}
+	return config, true
+}
+
+// blankAnswers 拆分学生答案，多空题以 JSON 数组按顺序提交各空答案
+func blankAnswers(answer string, count int) []string {
+	if count > 1 {
+		var answers []string
+		if err := json.Unmarshal([]byte(answer), &answers); err == nil {
+			return answers
+		}
+	}
+	return []string{answer}
+}
+
+// blankScore 为填空题评分，多空题逐空给分
+func blankScore(question models.Question, answer string) (int, string) {
+	config, ok := parseBlankConfig(question)
+	if !ok {
+		// 未配置时保持原有的精确匹配
+		if answer == question.CorrectAnswer {
+			return question.Score, "Correct"
+		}
+		return 0, "Incorrect"
+	}
+
+	answers := blankAnswers(answer, len(config.Blanks))
+	score, correct, hasBlankScores := 0, 0, false
+	results := make([]string, len(config.Blanks))
+	for i, spec := range config.Blanks {
+		if spec.Score > 0 {
+			hasBlankScores = true
+		}
+		results[i] = fmt.Sprintf("Blank %d: Incorrect", i+1)
+		if i < len(answers) && blankMatches(answers[i], spec, config) {
+			correct++
+			score += spec.Score
+			results[i] = fmt.Sprintf("Blank %d: Correct", i+1)
+		}
+	}
+	if !hasBlankScores {
+		score = question.Score * correct / len(config.Blanks)
+	}
+
+	if len(config.Blanks) > 1 {
+		return score, strings.Join(results, "; ")
+	}
+	if correct == 1 {
+		return score, "Correct"
+	}
+	return score, "Incorrect"
+}
+
+// blankConfigJSON 校验教师提交的填空配置并序列化，同时返回用于展示的参考答案
+func blankConfigJSON(content, correctAnswer string, score int, config *BlankConfig) (string, string, error) {
+	if config == nil || len(config.Blanks) == 0 {
+		if correctAnswer == "" {
+			return "", "", errors.New("填空题需要提供正确答案")
+		}
+		return "", correctAnswer, nil
+	}
+	if placeholders := blankPlaceholder.FindAllString(content, -1); len(placeholders) > 0 && len(placeholders) != len(config.Blanks) {
+		return "", "", fmt.Errorf("题干中有 %d 个空，但提供了 %d 组答案", len(placeholders), len(config.Blanks))
+	}
+	display := make([]string, len(config.Blanks))
+	for i, spec := range config.Blanks {
+		if len(spec.Answers) == 0 && spec.Regex == "" {
+			return "", "", fmt.Errorf("第 %d 个空需要提供答案或正则表达式", i+1)
+		}
+		if spec.Regex != "" {
+			if _, err := blankRegexp(spec, *config); err != nil {
+				return "", "", fmt.Errorf("第 %d 个空的正则表达式无效: %v", i+1, err)
+			}
+		}
+		if len(spec.Answers) > 0 {
+			display[i] = spec.Answers[0]
+		} else {
+			display[i] = spec.Regex
+		}
+	}
+	if correctAnswer == "" {
+		correctAnswer = strings.Join(display, "; ")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", "", err
+	}
+	if err := validateBlankScores(string(data), score); err != nil {
+		return "", "", err
+	}
+	return string(data), correctAnswer, nil
+}
+
+// validateBlankScores 校验各空分值之和与题目分值一致
+func validateBlankScores(config string, score int) error {
+	var parsed BlankConfig
+	if config == "" || json.Unmarshal([]byte(config), &parsed) != nil {
+		return nil
+	}
+	total := 0
+	for _, spec := range parsed.Blanks {
+		total += spec.Score
+	}
+	if total > 0 && total != score {
+		return fmt.Errorf("各空分值之和 %d 与题目分值 %d 不一致", total, score)
+	}
+	return nil
+}
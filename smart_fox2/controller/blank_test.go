@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"bytes"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlankScore_Normalization(t *testing.T) {
+	config := &BlankConfig{
+		Blanks:        []BlankSpec{{Answers: []string{"O(n)", "线性"}}},
+		IgnoreCase:    true,
+		CollapseSpace: true,
+		FullWidth:     true,
+	}
+	blankConfig, correctAnswer, err := blankConfigJSON("时间复杂度是？", "", 4, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "O(n)", correctAnswer)
+	question := models.Question{Type: "blank", Score: 4, CorrectAnswer: correctAnswer, BlankConfig: blankConfig}
+
+	for _, answer := range []string{"O(n)", "o(n)", "  O(N) ", "Ｏ（ｎ）", "线性"} {
+		score, feedback := blankScore(question, answer)
+		assert.Equal(t, 4, score, answer)
+		assert.Equal(t, "Correct", feedback)
+	}
+	score, _ := blankScore(question, "O(n^2)")
+	assert.Equal(t, 0, score)
+}
+
+func TestBlankScore_RegexAndMultiBlank(t *testing.T) {
+	config := &BlankConfig{
+		Blanks: []BlankSpec{
+			{Regex: `\d+\s*ms`},
+			{Answers: []string{"TCP"}, Score: 4},
+		},
+		IgnoreCase: true,
+	}
+	_, _, err := blankConfigJSON("延迟 {{1}}，协议 {{2}}", "", 5, config)
+	assert.Error(t, err, "各空分值之和与题目分值不一致")
+
+	config.Blanks[0].Score = 2
+	blankConfig, _, err := blankConfigJSON("延迟 {{1}}，协议 {{2}}", "", 6, config)
+	assert.NoError(t, err)
+	question := models.Question{Type: "blank", Score: 6, BlankConfig: blankConfig}
+
+	score, feedback := blankScore(question, `["120 MS","tcp"]`)
+	assert.Equal(t, 6, score)
+	assert.Equal(t, "Blank 1: Correct; Blank 2: Correct", feedback)
+
+	score, feedback = blankScore(question, `["abc","tcp"]`)
+	assert.Equal(t, 4, score)
+	assert.Equal(t, "Blank 1: Incorrect; Blank 2: Correct", feedback)
+
+	_, _, err = blankConfigJSON("{{1}} {{2}} {{3}}", "", 6, config)
+	assert.Error(t, err)
+	_, _, err = blankConfigJSON("", "", 1, &BlankConfig{Blanks: []BlankSpec{{Regex: "("}}})
+	assert.Error(t, err)
+	_, _, err = blankConfigJSON("", "", 1, nil)
+	assert.Error(t, err)
+}
+
+func TestSubmitExperiment_MultiBlankEvenSplit(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:         uuid.New().String(),
+		Title:      "Blanks",
+		Permission: 1,
+		Deadline:   time.Now().Add(24 * time.Hour),
+		Users:      []models.User{user},
+		Questions: []models.Question{
+			{
+				ID:          uuid.New().String(),
+				Type:        "blank",
+				Content:     "栈是 {{1}}，队列是 {{2}}",
+				BlankConfig: `{"blanks":[{"answers":["LIFO"]},{"answers":["FIFO"]}],"ignore_case":true}`,
+				Score:       10,
+			},
+		},
+	}
+	db.Create(&exp)
+
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"blank","answer":"[\"lifo\",\"LILO\"]"}]}`
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"total_score":"5/10"`), w.Body.String())
+}
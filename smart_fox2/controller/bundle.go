@@ -0,0 +1,537 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// bundleVersion 实验导出包的格式版本
+	bundleVersion = 1
+	// maxBundleFileSize 导入包中单个文件的最大大小
+	maxBundleFileSize = 50 << 20
+)
+
+// maxBundleTotalSize 压缩包解压后全部文件的最大总大小
+var maxBundleTotalSize int64 = 200 << 20
+
+// ExperimentBundle 实验导出包，包含题目、抽题规则、附件和实验设置，
+// 不包含学生、分组、课程和提交记录，导入时作为新实验创建
+type ExperimentBundle struct {
+	Version          int                `json:"version"`
+	Title            string             `json:"title" binding:"required"`
+	Description      string             `json:"description,omitempty"`
+	Permission       int                `json:"permission" binding:"oneof=0 1"`
+	Deadline         *time.Time         `json:"deadline,omitempty"` // 导出时的截止时间，导入时可重新指定
+	DurationMinutes  int                `json:"duration_minutes,omitempty" binding:"gte=0"`
+	MaxAttempts      int                `json:"max_attempts,omitempty" binding:"gte=0"`
+	ShuffleQuestions bool               `json:"shuffle_questions,omitempty"`
+	ShuffleOptions   bool               `json:"shuffle_options,omitempty"`
+	ScoringPolicy    string             `json:"scoring_policy,omitempty" binding:"omitempty,oneof=best last average"`
+	LatePolicy       *LatePolicyInput   `json:"late_policy,omitempty"`
+	Pools            []PoolInput        `json:"pools,omitempty" binding:"omitempty,dive"`
+	Questions        []BundleQuestion   `json:"questions" binding:"required,min=1,dive"`
+	Attachments      []BundleAttachment `json:"attachments,omitempty"`
+}
+
+// BundleQuestion 导出包中的题目，结构化保存选项、测试用例等内容，便于直接编辑。
+// 字段与 QuestionInput 一一对应，只是不强制题型相关的必填项，以便导入由更新实验修改过的题目
+type BundleQuestion struct {
+	Type              string                   `json:"type" binding:"required,oneof=choice multi_choice blank numeric true_false essay code"`
+	Content           string                   `json:"content" binding:"required"`
+	Options           []string                 `json:"options,omitempty"`
+	CorrectAnswer     string                   `json:"correct_answer,omitempty"`
+	BlankConfig       *BlankConfig             `json:"blank_config,omitempty"`
+	Rubric            []RubricCriterion        `json:"rubric,omitempty"`
+	CorrectAnswers    []string                 `json:"correct_answers,omitempty"` // 多选题的全部正确选项
+	ScoringMode       string                   `json:"scoring_mode,omitempty" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
+	Tolerance         float64                  `json:"tolerance,omitempty" binding:"gte=0"`
+	ToleranceType     string                   `json:"tolerance_type,omitempty" binding:"omitempty,oneof=absolute relative"`
+	Unit              string                   `json:"unit,omitempty" binding:"omitempty,max=20"`
+	Score             int                      `json:"score" binding:"required,gt=0"`
+	ImageURL          string                   `json:"image_url,omitempty"`
+	Explanation       string                   `json:"explanation,omitempty"`
+	TestCases         []TestCase               `json:"test_cases,omitempty" binding:"omitempty,dive"`
+	TimeLimit         int                      `json:"time_limit,omitempty" binding:"gte=0,lte=60"`
+	MemoryLimitMB     int                      `json:"memory_limit_mb,omitempty" binding:"gte=0,lte=4096"`
+	LanguageLimits    map[string]ResourceLimit `json:"language_limits,omitempty" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	CompareMode       string                   `json:"compare_mode,omitempty" binding:"omitempty,oneof=exact ignore_whitespace float checker"`
+	FloatEpsilon      float64                  `json:"float_epsilon,omitempty" binding:"gte=0"`
+	Checker           *CheckerProgram          `json:"checker,omitempty"`
+	StarterCode       map[string]string        `json:"starter_code,omitempty" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	Harness           map[string]string        `json:"harness,omitempty" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	ReferenceSolution *ReferenceSolution       `json:"reference_solution,omitempty"`
+	ValidateTestCases string                   `json:"validate_test_cases,omitempty" binding:"omitempty,oneof=reject flag"` // 导入时用参考答案校验测试用例
+	Pool              string                   `json:"pool,omitempty" binding:"omitempty,max=50"`
+	Difficulty        string                   `json:"difficulty,omitempty" binding:"omitempty,oneof=easy medium hard"`
+}
+
+// BundleAttachment 导出包中的附件，File 为压缩包内的文件路径，未打包文件时为空
+type BundleAttachment struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+}
+
+// bundleQuestion 将题目转换为导出包中的结构
+func bundleQuestion(q models.Question) BundleQuestion {
+	return BundleQuestion(questionInput(q))
+}
+
+// toQuestion 将导出包中的题目转换为新题目，与创建实验时做相同的校验
+func (bq BundleQuestion) toQuestion(experimentID string) (models.Question, *TestCaseWarning, error) {
+	return buildQuestion(uuid.NewString(), experimentID, QuestionInput(bq))
+}
+
+// marshalBundle 按格式序列化导出包，YAML 与 JSON 使用相同的字段名
+func marshalBundle(bundle ExperimentBundle, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil || format != "yaml" {
+		return data, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// unmarshalBundle 解析 JSON 或 YAML 格式的导出包
+func unmarshalBundle(data []byte, format string) (ExperimentBundle, error) {
+	var bundle ExperimentBundle
+	if format == "yaml" {
+		var generic interface{}
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return bundle, err
+		}
+		var err error
+		if data, err = json.Marshal(jsonCompatible(generic)); err != nil {
+			return bundle, err
+		}
+	}
+	err := json.Unmarshal(data, &bundle)
+	return bundle, err
+}
+
+// jsonCompatible 将 YAML 解析出的 map[interface{}]interface{} 转换为可以 JSON 序列化的结构
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+	}
+	return value
+}
+
+// bundleFormat 根据文件扩展名判断导出包格式
+func bundleFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".zip":
+		return "zip"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
+
+// localAttachmentPath 本地保存的附件路径，附件不在本地时返回空
+func localAttachmentPath(attachment models.Attachment) string {
+	if !strings.HasPrefix(attachment.URL, "/uploads/") {
+		return ""
+	}
+	return filepath.Join("uploads", filepath.Base(attachment.URL))
+}
+
+// ExportExperiment 导出实验，format 为 json（默认）或 yaml，zip=true 时连同附件一起打包
+func ExportExperiment(c *gin.Context) {
+	db := global.DB
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "format 参数只能是 json 或 yaml"})
+		return
+	}
+	withFiles := c.Query("zip") == "true" || c.Query("zip") == "1"
+
+	var questions []models.Question
+	var pools []models.QuestionPool
+	var attachments []models.Attachment
+	if err := db.Where("experiment_id = ?", experiment.ID).Order("created_at ASC").Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		return
+	}
+	db.Where("experiment_id = ?", experiment.ID).Find(&pools)
+	db.Where("experiment_id = ?", experiment.ID).Find(&attachments)
+
+	deadline := experiment.Deadline
+	bundle := ExperimentBundle{
+		Version:          bundleVersion,
+		Title:            experiment.Title,
+		Description:      experiment.Description,
+		Permission:       experiment.Permission,
+		Deadline:         &deadline,
+		DurationMinutes:  experiment.DurationMinutes,
+		MaxAttempts:      experiment.MaxAttempts,
+		ShuffleQuestions: experiment.ShuffleQuestions,
+		ShuffleOptions:   experiment.ShuffleOptions,
+		ScoringPolicy:    experiment.ScoringPolicy,
+		Questions:        make([]BundleQuestion, len(questions)),
+	}
+	if experiment.LatePenaltyPercent > 0 {
+		bundle.LatePolicy = &LatePolicyInput{
+			PenaltyPercent: experiment.LatePenaltyPercent,
+			PenaltyUnit:    experiment.LatePenaltyUnit,
+			GraceMinutes:   experiment.LateGraceMinutes,
+			CutoffHours:    experiment.LateCutoffHours,
+			MaxPenalty:     experiment.LateMaxPenalty,
+		}
+	}
+	for _, p := range pools {
+		bundle.Pools = append(bundle.Pools, PoolInput{Pool: p.Pool, Difficulty: p.Difficulty, DrawCount: p.DrawCount})
+	}
+	for i, q := range questions {
+		bundle.Questions[i] = bundleQuestion(q)
+	}
+
+	// 打包时附件放在 attachments 目录下，按序号命名避免重名
+	files := make(map[string]string)
+	for i, attachment := range attachments {
+		item := BundleAttachment{Name: attachment.Name}
+		if localPath := localAttachmentPath(attachment); withFiles && localPath != "" {
+			if _, err := os.Stat(localPath); err == nil {
+				item.File = fmt.Sprintf("attachments/%d_%s", i+1, filepath.Base(attachment.Name))
+				files[item.File] = localPath
+			}
+		}
+		bundle.Attachments = append(bundle.Attachments, item)
+	}
+
+	data, err := marshalBundle(bundle, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "导出失败"})
+		return
+	}
+	filename := "experiment-" + experiment.ID
+	if !withFiles {
+		contentType := "application/json"
+		if format == "yaml" {
+			contentType = "application/x-yaml"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+		c.Data(http.StatusOK, contentType, data)
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	err = func() error {
+		w, err := archive.Create("experiment." + format)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		for name, localPath := range files {
+			content, err := os.ReadFile(localPath)
+			if err != nil {
+				return err
+			}
+			w, err := archive.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(content); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	}()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "打包附件失败"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// readZipFile 读取压缩包中的文件，超过大小限制时返回错误
+func readZipFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxBundleFileSize {
+		return nil, fmt.Errorf("文件 %s 过大", file.Name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxBundleFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleFileSize {
+		return nil, fmt.Errorf("文件 %s 过大", file.Name)
+	}
+	return data, nil
+}
+
+// readBundle 从上传的文件读取导出包，压缩包同时返回其中的附件文件
+func readBundle(c *gin.Context) (ExperimentBundle, map[string][]byte, error) {
+	var bundle ExperimentBundle
+	file, err := c.FormFile("file")
+	if err != nil {
+		return bundle, nil, errors.New("请上传导出包文件")
+	}
+	format := bundleFormat(file.Filename)
+	if format == "" {
+		return bundle, nil, errors.New("导出包必须是 .json、.yaml、.yml 或 .zip 文件")
+	}
+	if file.Size > maxBundleFileSize {
+		return bundle, nil, errors.New("导出包文件过大")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return bundle, nil, err
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return bundle, nil, err
+	}
+	if format != "zip" {
+		bundle, err = unmarshalBundle(data, format)
+		return bundle, nil, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return bundle, nil, errors.New("无法读取压缩包")
+	}
+	files := make(map[string][]byte)
+	var manifest []byte
+	manifestFormat := ""
+	// 压缩包头中记录的大小可以伪造，按实际读出的字节数累计
+	var total int64
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return bundle, nil, err
+		}
+		if total += int64(len(content)); total > maxBundleTotalSize {
+			return bundle, nil, errors.New("压缩包解压后过大")
+		}
+		switch name := path.Clean(f.Name); name {
+		case "experiment.json", "experiment.yaml", "experiment.yml":
+			manifest, manifestFormat = content, bundleFormat(name)
+		default:
+			files[name] = content
+		}
+	}
+	if manifest == nil {
+		return bundle, nil, errors.New("压缩包中缺少 experiment.json 或 experiment.yaml")
+	}
+	bundle, err = unmarshalBundle(manifest, manifestFormat)
+	return bundle, files, err
+}
+
+// ImportExperiment 从导出包创建新实验。表单字段 file 为导出包，deadline 和 opens_at 可重新指定时间，
+// title、course_id、student_ids、group_ids 可选；validate_test_cases 为未单独设置校验方式的代码题
+// 指定参考答案校验方式；只有压缩包中带有文件的附件会被导入
+func ImportExperiment(c *gin.Context) {
+	db := global.DB
+	bundle, files, err := readBundle(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "导出包解析失败: " + err.Error()})
+		return
+	}
+	if bundle.Version > bundleVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": fmt.Sprintf("不支持的导出包版本 %d", bundle.Version)})
+		return
+	}
+	if title := c.PostForm("title"); title != "" {
+		bundle.Title = title
+	}
+	if mode := c.PostForm("validate_test_cases"); mode != "" {
+		for i := range bundle.Questions {
+			if bundle.Questions[i].Type == "code" && bundle.Questions[i].ValidateTestCases == "" {
+				bundle.Questions[i].ValidateTestCases = mode
+			}
+		}
+	}
+	if err := binding.Validator.ValidateStruct(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "导出包内容无效: " + err.Error()})
+		return
+	}
+
+	// 重新指定的时间优先于导出时的截止时间
+	var deadline time.Time
+	if bundle.Deadline != nil {
+		deadline = *bundle.Deadline
+	}
+	if value := c.PostForm("deadline"); value != "" {
+		if deadline, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "deadline 格式应为 RFC3339"})
+			return
+		}
+	}
+	if deadline.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "截止日期必须在未来，请重新指定 deadline"})
+		return
+	}
+	var opensAt *time.Time
+	if value := c.PostForm("opens_at"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil || !t.Before(deadline) {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "开放时间必须早于截止日期"})
+			return
+		}
+		opensAt = &t
+	}
+
+	teacher := currentUser(c)
+	courseID := c.PostForm("course_id")
+	if courseID != "" && !canManageCourse(db, courseID, teacher.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "无权在该课程下创建实验"})
+		return
+	}
+	users, err := findAssignedStudents(db, c.PostFormArray("student_ids"), courseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	groups, err := findGroups(db, c.PostFormArray("group_ids"), courseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	experimentID := uuid.NewString()
+	now := time.Now()
+	experiment := models.Experiment{
+		ID:               experimentID,
+		Title:            bundle.Title,
+		Description:      bundle.Description,
+		Permission:       bundle.Permission,
+		CourseID:         courseID,
+		OpensAt:          opensAt,
+		Deadline:         deadline,
+		DurationMinutes:  bundle.DurationMinutes,
+		MaxAttempts:      bundle.MaxAttempts,
+		ShuffleQuestions: bundle.ShuffleQuestions,
+		ShuffleOptions:   bundle.ShuffleOptions,
+		ScoringPolicy:    bundle.ScoringPolicy,
+		TeacherID:        teacher.ID,
+		Users:            users,
+		Groups:           groups,
+		Pools:            toQuestionPools(bundle.Pools, experimentID),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if bundle.LatePolicy != nil {
+		bundle.LatePolicy.apply(&experiment)
+	}
+	var warnings []TestCaseWarning
+	for i, bq := range bundle.Questions {
+		question, warning, err := bq.toQuestion(experimentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": fmt.Sprintf("第 %d 题: %v", i+1, err)})
+			return
+		}
+		if warning != nil {
+			warnings = append(warnings, *warning)
+		}
+		question.CreatedAt = now.Add(time.Duration(i) * time.Millisecond) // 保持题目顺序
+		question.UpdatedAt = now
+		experiment.Questions = append(experiment.Questions, question)
+	}
+	if err := validatePools(experiment.Questions, experiment.Pools); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	// 保存压缩包中的附件
+	var saved []string
+	removeSaved := func() {
+		for _, p := range saved {
+			os.Remove(p)
+		}
+	}
+	for _, item := range bundle.Attachments {
+		content, ok := files[path.Clean(item.File)]
+		if item.File == "" || !ok {
+			continue
+		}
+		if err := os.MkdirAll("uploads", 0755); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "无法创建上传目录"})
+			return
+		}
+		fileName := uuid.New().String() + filepath.Ext(item.Name)
+		filePath := filepath.Join("uploads", fileName)
+		if err := os.WriteFile(filePath, content, 0644); err != nil {
+			removeSaved()
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "无法保存附件"})
+			return
+		}
+		saved = append(saved, filePath)
+		experiment.Attachments = append(experiment.Attachments, models.Attachment{
+			ExperimentID: experimentID,
+			Name:         item.Name,
+			URL:          "/uploads/" + fileName,
+		})
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&experiment).Error
+	}); err != nil {
+		removeSaved()
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "导入实验失败"})
+		return
+	}
+	if len(users) > 0 || len(groups) > 0 {
+		notifyNewExperiment(db, experiment, teacher.ID)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   "success",
+		"message":  "实验已导入",
+		"warnings": warnings,
+		"data": gin.H{
+			"experiment_id": experiment.ID,
+			"title":         experiment.Title,
+			"questions":     len(experiment.Questions),
+			"attachments":   len(experiment.Attachments),
+			"skipped":       len(bundle.Attachments) - len(experiment.Attachments),
+		},
+	})
+}
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// createBundleExperiment 创建一个包含多种题型、抽题规则和本地附件的实验
+func createBundleExperiment(t *testing.T, teacher models.User) models.Experiment {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("uploads", 0755)
+	os.WriteFile(filepath.Join("uploads", "guide.pdf"), []byte("guide"), 0644)
+
+	now := time.Now()
+	exp := models.Experiment{
+		ID:                 "exp-bundle",
+		Title:              "期中实验",
+		Description:        "综合练习",
+		Permission:         1,
+		Deadline:           now.Add(24 * time.Hour),
+		MaxAttempts:        2,
+		ShuffleOptions:     true,
+		LatePenaltyPercent: 10,
+		LatePenaltyUnit:    "day",
+		TeacherID:          teacher.ID,
+		Pools:              []models.QuestionPool{{ExperimentID: "exp-bundle", Pool: "基础", DrawCount: 1}},
+		Attachments:        []models.Attachment{{ExperimentID: "exp-bundle", Name: "实验指导.pdf", URL: "/uploads/guide.pdf"}},
+		Questions: []models.Question{
+			{ID: "q-choice", Type: "choice", Content: "1+1=?", Score: 2, Options: `["1","2"]`, CorrectAnswer: "B",
+				Explanation: "基础加法", Pool: "基础", CreatedAt: now},
+			{ID: "q-multi", Type: "multi_choice", Content: "偶数", Score: 3, Options: `["1","2","4"]`, CorrectAnswer: `["2","4"]`,
+				ScoringMode: "proportional", CreatedAt: now.Add(time.Second)},
+			{ID: "q-code", Type: "code", Content: "a+b", Score: 10, CreatedAt: now.Add(2 * time.Second),
+				TestCases:   `[{"input":"1 2","expected_output":"3","sample":true},{"input":"2 2","expected_output":"4","weight":2}]`,
+				CompareMode: compareFloat, FloatEpsilon: 0.001, TimeLimit: 3,
+				StarterCode: `{"python":"def add(a, b):\n    pass"}`},
+		},
+	}
+	assert.NoError(t, global.DB.Create(&exp).Error)
+	return exp
+}
+
+func exportBundle(teacher models.User, experimentID, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: experimentID}}
+	c.Request = httptest.NewRequest("GET", "/export?"+query, nil)
+	ExportExperiment(c)
+	return w
+}
+
+func importBundle(teacher models.User, filename string, data []byte, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(data)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Request = httptest.NewRequest("POST", "/experiments/import", &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	ImportExperiment(c)
+	return w
+}
+
+// importedExperiment 读取导入接口返回的新实验及其题目
+func importedExperiment(t *testing.T, w *httptest.ResponseRecorder) models.Experiment {
+	var resp struct {
+		Data struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	var exp models.Experiment
+	assert.NoError(t, global.DB.Preload("Questions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Pools").Preload("Attachments").First(&exp, "id = ?", resp.Data.ExperimentID).Error)
+	return exp
+}
+
+func TestExportImportExperiment(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	original := createBundleExperiment(t, teacher)
+
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			w := exportBundle(teacher, original.ID, "format="+format)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Disposition"), "."+format)
+
+			w = importBundle(teacher, "bundle."+format, w.Body.Bytes(), nil)
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+			exp := importedExperiment(t, w)
+			assert.NotEqual(t, original.ID, exp.ID)
+			assert.Equal(t, "期中实验", exp.Title)
+			assert.Equal(t, 2, exp.MaxAttempts)
+			assert.True(t, exp.ShuffleOptions)
+			assert.Equal(t, 10.0, exp.LatePenaltyPercent)
+			assert.Equal(t, teacher.ID, exp.TeacherID)
+			assert.Len(t, exp.Pools, 1)
+			// 未打包附件文件时不导入附件
+			assert.Empty(t, exp.Attachments)
+			if assert.Len(t, exp.Questions, 3) {
+				assert.Equal(t, "B", exp.Questions[0].CorrectAnswer)
+				assert.Equal(t, "基础加法", exp.Questions[0].Explanation)
+				assert.Equal(t, `["2","4"]`, exp.Questions[1].CorrectAnswer)
+				assert.Equal(t, "proportional", exp.Questions[1].ScoringMode)
+				code := exp.Questions[2]
+				assert.Equal(t, compareFloat, code.CompareMode)
+				assert.Equal(t, 3, code.TimeLimit)
+				assert.Equal(t, map[string]string{"python": "def add(a, b):\n    pass"}, parseLanguageCode(code.StarterCode))
+				var testCases []TestCase
+				json.Unmarshal([]byte(code.TestCases), &testCases)
+				if assert.Len(t, testCases, 2) {
+					assert.True(t, testCases[0].Sample)
+					assert.Equal(t, 2.0, testCases[1].Weight)
+				}
+			}
+		})
+	}
+}
+
+func TestExportImportExperiment_Zip(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	original := createBundleExperiment(t, teacher)
+
+	w := exportBundle(teacher, original.ID, "format=yaml&zip=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if assert.NoError(t, err) {
+		names := make([]string, 0)
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"experiment.yaml", "attachments/1_实验指导.pdf"}, names)
+	}
+
+	// 截止时间已过时需要重新指定
+	deadline := time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	w = importBundle(teacher, "bundle.zip", w.Body.Bytes(), map[string]string{"title": "期中实验（副本）", "deadline": deadline})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	exp := importedExperiment(t, w)
+	assert.Equal(t, "期中实验（副本）", exp.Title)
+	assert.Equal(t, deadline, exp.Deadline.Format(time.RFC3339))
+	if assert.Len(t, exp.Attachments, 1) {
+		assert.Equal(t, "实验指导.pdf", exp.Attachments[0].Name)
+		assert.NotEqual(t, "/uploads/guide.pdf", exp.Attachments[0].URL)
+		content, _ := os.ReadFile(localAttachmentPath(exp.Attachments[0]))
+		assert.Equal(t, "guide", string(content))
+	}
+}
+
+func TestImportExperiment_Invalid(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+
+	w := importBundle(teacher, "bundle.txt", []byte("{}"), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = importBundle(teacher, "bundle.json", []byte(`{"title":"空实验","questions":[]}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	past := `{"title":"旧实验","deadline":"2020-01-01T00:00:00Z","questions":[{"type":"essay","content":"总结","score":5}]}`
+	w = importBundle(teacher, "bundle.json", []byte(past), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "截止日期必须在未来")
+}
+
+func TestImportExperiment_ValidateTestCases(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	fakeJudgeOutputs(t, "3", "5")
+
+	bundle := `{"title":"加法","deadline":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `","questions":[{"type":"code","content":"a+b","score":10,
+		"test_cases":[{"input":"1 2","expected_output":"3"},{"input":"2 2","expected_output":"4"}],
+		"reference_solution":{"language":"python","source_code":"print(sum(map(int, input().split())))"}}]}`
+
+	// 与创建实验相同，reject 时参考答案未通过则拒绝导入
+	w := importBundle(teacher, "bundle.json", []byte(bundle), map[string]string{"validate_test_cases": "reject"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "参考答案未通过测试用例 2")
+
+	w = importBundle(teacher, "bundle.json", []byte(bundle), map[string]string{"validate_test_cases": "flag"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp struct {
+		Warnings []TestCaseWarning `json:"warnings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if assert.Len(t, resp.Warnings, 1) && assert.Len(t, resp.Warnings[0].Failures, 1) {
+		assert.Equal(t, 2, resp.Warnings[0].Failures[0].Index)
+	}
+
+	w = importBundle(teacher, "bundle.json", []byte(bundle), map[string]string{"validate_test_cases": "maybe"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportExperiment_TotalSizeLimit(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	limit := maxBundleTotalSize
+	maxBundleTotalSize = 1024
+	t.Cleanup(func() { maxBundleTotalSize = limit })
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	manifest, _ := archive.Create("experiment.json")
+	manifest.Write([]byte(`{"title":"附件","questions":[{"type":"essay","content":"总结","score":5}]}`))
+	// 单个文件未超过限制，但解压后的总大小超过限制
+	for _, name := range []string{"attachments/a.bin", "attachments/b.bin"} {
+		f, _ := archive.Create(name)
+		f.Write(make([]byte, 600))
+	}
+	archive.Close()
+
+	w := importBundle(teacher, "bundle.zip", buf.Bytes(), map[string]string{"deadline": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "压缩包解压后过大")
+}
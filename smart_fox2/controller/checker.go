@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"lh/models"
+)
+
+// 代码题输出比较方式
+const (
+	compareExact            = "exact"             // 逐行比较，忽略行尾空白（默认）
+	compareIgnoreWhitespace = "ignore_whitespace" // 按空白分词后比较
+	compareFloat            = "float"             // 数值在误差范围内视为相同
+	compareChecker          = "checker"           // 由检查程序判定，用于多解题目
+)
+
+// CheckerProgram 检查程序，评测服务以 checker <input> <output> <answer> 调用，
+// 退出码 0 为通过、1 为答案错误，输出到 stderr 的信息作为该用例的提示
+type CheckerProgram struct {
+	Language   string `json:"language" binding:"required,oneof=cpp java python"`
+	SourceCode string `json:"source_code" binding:"required"`
+}
+
+// parseChecker 读取题目的检查程序，未设置时返回 nil
+func parseChecker(question models.Question) *CheckerProgram {
+	var checker CheckerProgram
+	if question.Checker == "" || json.Unmarshal([]byte(question.Checker), &checker) != nil {
+		return nil
+	}
+	return &checker
+}
+
+// compareMode 题目的输出比较方式，未设置时为逐行比较
+func compareMode(question models.Question) string {
+	if question.CompareMode == "" {
+		return compareExact
+	}
+	return question.CompareMode
+}
+
+// checkerJSON 校验比较方式与检查程序是否匹配并序列化检查程序，只提供检查程序时比较方式视为 checker
+func checkerJSON(mode string, checker *CheckerProgram) (string, string, error) {
+	if checker != nil && mode == "" {
+		mode = compareChecker
+	}
+	if mode == compareChecker && checker == nil {
+		return "", "", errors.New("checker 比较方式需要提供检查程序")
+	}
+	if mode != compareChecker && checker != nil {
+		return "", "", errors.New("只有 checker 比较方式可以设置检查程序")
+	}
+	if checker == nil {
+		return mode, "", nil
+	}
+	return mode, toJSONString(checker), nil
+}
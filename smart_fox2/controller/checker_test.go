@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerJSON(t *testing.T) {
+	checker := &CheckerProgram{Language: "python", SourceCode: "import sys"}
+
+	mode, data, err := checkerJSON("", checker)
+	assert.NoError(t, err)
+	assert.Equal(t, compareChecker, mode)
+	assert.Equal(t, checker, parseChecker(models.Question{Checker: data}))
+
+	_, _, err = checkerJSON(compareChecker, nil)
+	assert.Error(t, err)
+	_, _, err = checkerJSON(compareFloat, checker)
+	assert.Error(t, err)
+
+	mode, data, err = checkerJSON(compareFloat, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, compareFloat, mode)
+	assert.Empty(t, data)
+	assert.Equal(t, compareExact, compareMode(models.Question{}))
+}
+
+func TestEvaluateCode_CheckerVerdicts(t *testing.T) {
+	var received EvaluationRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(EvaluationResponse{CaseResults: []EvaluationCaseResult{
+			{Status: statusAccepted, CheckerMessage: "ok"},
+			{Status: "Wrong Answer", CheckerMessage: "edge 1->2 violated"},
+		}})
+	}))
+	defer srv.Close()
+	t.Setenv("JUDGE_URL", srv.URL)
+
+	question := models.Question{Type: "code", Score: 10, CompareMode: compareChecker,
+		Checker:   `{"language":"python","source_code":"import sys"}`,
+		TestCases: `[{"input":"1 2","expected_output":"1 2","sample":true},{"input":"2 1","expected_output":"2 1"}]`}
+	score, feedback, detail := gradeAnswer(question, submittedAnswer{Type: "code", Code: "print(input())", Language: "python"})
+	assert.Equal(t, compareChecker, received.CompareMode)
+	if assert.NotNil(t, received.Checker) {
+		assert.Equal(t, "import sys", received.Checker.SourceCode)
+	}
+	assert.Equal(t, 5, score)
+	assert.Equal(t, "Passed 1/2 test cases", feedback)
+
+	result := parseJudgeResult(models.QuestionSubmission{JudgeResult: detail})
+	if assert.NotNil(t, result) && assert.Len(t, result.Cases, 2) {
+		assert.Equal(t, "ok", result.Cases[0].Message)
+		// 检查器信息可能包含隐藏用例的数据，只对样例展示
+		assert.Empty(t, result.Cases[1].Message)
+		assert.Equal(t, "Wrong Answer", result.Cases[1].Status)
+	}
+}
+
+func TestCreateExperiment_CheckerValidation(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	teacher := models.User{Name: "teacher", Role: "teacher"}
+	db.Create(&teacher)
+	create := func(question string) *httptest.ResponseRecorder {
+		body := `{"title":"拓扑排序","permission":1,"student_ids":["1"],"deadline":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `",
+			"questions":[` + question + `]}`
+		c, w := setupTestContext(teacher)
+		c.Request, _ = http.NewRequest("POST", "/experiments", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CreateExperiment(c)
+		return w
+	}
+
+	w := create(`{"type":"code","content":"拓扑排序","score":10,"test_cases":[{"input":"1","expected_output":"1"}],"compare_mode":"checker"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "需要提供检查程序")
+
+	w = create(`{"type":"code","content":"拓扑排序","score":10,"test_cases":[{"input":"1","expected_output":"1"}],
+		"checker":{"language":"python","source_code":"import sys"}}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var question models.Question
+	db.First(&question, "type = ?", "code")
+	assert.Equal(t, compareChecker, question.CompareMode)
+	assert.NotNil(t, parseChecker(question))
+}
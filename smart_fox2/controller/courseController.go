@@ -0,0 +1,517 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"lh/common"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 辅助函数：判断教师是否为课程的任课教师
+func canManageCourse(db *gorm.DB, courseID string, teacherID uint) bool {
+	var count int64
+	db.Table("course_teachers").
+		Where("course_id = ? AND user_id = ?", courseID, teacherID).
+		Count(&count)
+	return count > 0
+}
+
+// 辅助函数：判断学生是否选修了课程
+func isEnrolledInCourse(db *gorm.DB, courseID string, studentID uint) bool {
+	var count int64
+	db.Table("course_students").
+		Where("course_id = ? AND user_id = ?", courseID, studentID).
+		Count(&count)
+	return count > 0
+}
+
+// 辅助函数：校验学生是否都已选修课程
+func checkCourseStudents(db *gorm.DB, courseID string, studentIDs []uint) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Table("course_students").
+		Where("course_id = ? AND user_id IN ?", courseID, studentIDs).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(studentIDs) {
+		return errors.New("部分学生未选修该课程")
+	}
+	return nil
+}
+
+// 辅助函数：按角色查找用户，ID 列表中有不存在或角色不符的用户时返回错误
+func findUsersByRole(db *gorm.DB, ids []string, role string) ([]models.User, error) {
+	userIDs := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool)
+	for _, idStr := range ids {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的用户ID: %s", idStr)
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			userIDs = append(userIDs, uint(id))
+		}
+	}
+	var users []models.User
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	if err := db.Where("id IN ? AND role = ?", userIDs, role).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != len(userIDs) {
+		return nil, fmt.Errorf("部分用户不存在或不是%s角色", role)
+	}
+	return users, nil
+}
+
+func userIDStrings(users []models.User) []string {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = strconv.FormatUint(uint64(u.ID), 10)
+	}
+	return ids
+}
+
+// CreateCourse 创建课程，创建者自动成为任课教师
+func CreateCourse(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required"`
+		Code        string   `json:"code"`
+		Term        string   `json:"term"`
+		Description string   `json:"description"`
+		TeacherIDs  []string `json:"teacher_ids"`
+		StudentIDs  []string `json:"student_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	db := global.DB
+	teacher := currentUser(c)
+
+	teachers, err := findUsersByRole(db, req.TeacherIDs, "teacher")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	students, err := findUsersByRole(db, req.StudentIDs, "student")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	hasCreator := false
+	for _, t := range teachers {
+		if t.ID == teacher.ID {
+			hasCreator = true
+		}
+	}
+	if !hasCreator {
+		teachers = append([]models.User{teacher}, teachers...)
+	}
+
+	course := models.Course{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Code:        req.Code,
+		Term:        req.Term,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Teachers:    teachers,
+		Students:    students,
+	}
+	if err := db.Create(&course).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建课程失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 201,
+		"data": gin.H{
+			"course_id":   course.ID,
+			"name":        course.Name,
+			"code":        course.Code,
+			"term":        course.Term,
+			"teacher_ids": userIDStrings(course.Teachers),
+			"student_ids": userIDStrings(course.Students),
+			"created_at":  course.CreatedAt.Format(time.RFC3339),
+		},
+		"message": "创建课程成功",
+	})
+}
+
+// GetCourses_Teacher 获取教师任教的课程列表
+func GetCourses_Teacher(c *gin.Context) {
+	db := common.GetDB()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	term := c.Query("term")
+	offset := (page - 1) * limit
+
+	teachingCourses := db.Table("course_teachers").
+		Select("course_id").
+		Where("user_id = ?", currentUser(c).ID)
+	query := db.Model(&models.Course{}).Where("id IN (?)", teachingCourses)
+	if term != "" {
+		query = query.Where("term = ?", term)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var courses []models.Course
+	if err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库查询失败",
+		})
+		return
+	}
+
+	response := make([]gin.H, len(courses))
+	for i, course := range courses {
+		var studentCount int64
+		db.Table("course_students").Where("course_id = ?", course.ID).Count(&studentCount)
+		response[i] = gin.H{
+			"course_id":     course.ID,
+			"name":          course.Name,
+			"code":          course.Code,
+			"term":          course.Term,
+			"description":   course.Description,
+			"student_count": studentCount,
+			"created_at":    course.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": response,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+		"message": "课程列表获取成功",
+	})
+}
+
+// GetCourseDetail_Teacher 获取课程详情，包括任课教师、学生、实验和分组
+func GetCourseDetail_Teacher(c *gin.Context) {
+	db := common.GetDB()
+	courseID := c.Param("course_id")
+
+	var course models.Course
+	if err := db.Preload("Teachers").Preload("Students").
+		First(&course, "id = ?", courseID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "课程不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "数据库查询失败"})
+		}
+		return
+	}
+	if !canManageCourse(db, courseID, currentUser(c).ID) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权访问该课程"})
+		return
+	}
+
+	var experiments []models.Experiment
+	db.Where("course_id = ?", courseID).Order("created_at DESC").Find(&experiments)
+	experimentResponses := make([]gin.H, len(experiments))
+	for i, exp := range experiments {
+		experimentResponses[i] = gin.H{
+			"experiment_id": exp.ID,
+			"title":         exp.Title,
+			"opens_at":      formatTimePtr(exp.OpensAt),
+			"deadline":      exp.Deadline.Format(time.RFC3339),
+			"status":        getExperimentStatus(exp.OpensAt, exp.Deadline),
+		}
+	}
+
+	var groups []models.Group
+	db.Where("course_id = ?", courseID).Find(&groups)
+	groupResponses := make([]gin.H, len(groups))
+	for i, group := range groups {
+		groupResponses[i] = gin.H{
+			"group_id":   strconv.FormatUint(uint64(group.ID), 10),
+			"group_name": group.Name,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"course_id":   course.ID,
+			"name":        course.Name,
+			"code":        course.Code,
+			"term":        course.Term,
+			"description": course.Description,
+			"teacher_ids": userIDStrings(course.Teachers),
+			"student_ids": userIDStrings(course.Students),
+			"experiments": experimentResponses,
+			"groups":      groupResponses,
+			"created_at":  course.CreatedAt.Format(time.RFC3339),
+		},
+		"message": "课程详情获取成功",
+	})
+}
+
+// UpdateCourse 更新课程信息，teacher_ids / student_ids 提供时整体替换
+func UpdateCourse(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name"`
+		Code        string   `json:"code"`
+		Term        string   `json:"term"`
+		Description string   `json:"description"`
+		TeacherIDs  []string `json:"teacher_ids"`
+		StudentIDs  []string `json:"student_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	courseID := c.Param("course_id")
+	teacherID := currentUser(c).ID
+
+	var course models.Course
+	if err := db.First(&course, "id = ?", courseID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "课程不存在"})
+		return
+	}
+	if !canManageCourse(db, courseID, teacherID) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权修改该课程"})
+		return
+	}
+
+	var teachers, students []models.User
+	var err error
+	if req.TeacherIDs != nil {
+		if teachers, err = findUsersByRole(db, req.TeacherIDs, "teacher"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+		if len(teachers) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "课程至少需要一名任课教师"})
+			return
+		}
+	}
+	if req.StudentIDs != nil {
+		if students, err = findUsersByRole(db, req.StudentIDs, "student"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if req.Name != "" {
+			course.Name = req.Name
+		}
+		if req.Code != "" {
+			course.Code = req.Code
+		}
+		if req.Term != "" {
+			course.Term = req.Term
+		}
+		if req.Description != "" {
+			course.Description = req.Description
+		}
+		course.UpdatedAt = time.Now()
+		if err := tx.Save(&course).Error; err != nil {
+			return err
+		}
+		if req.TeacherIDs != nil {
+			if err := tx.Model(&course).Association("Teachers").Replace(teachers); err != nil {
+				return err
+			}
+		}
+		if req.StudentIDs != nil {
+			if err := tx.Model(&course).Association("Students").Replace(students); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新课程失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"course_id":  course.ID,
+			"name":       course.Name,
+			"updated_at": course.UpdatedAt.Format(time.RFC3339),
+		},
+		"message": "课程更新成功",
+	})
+}
+
+// DeleteCourse 删除课程，已关联的实验、分组和通知保留但不再归属任何课程
+func DeleteCourse(c *gin.Context) {
+	db := global.DB
+	courseID := c.Param("course_id")
+
+	var course models.Course
+	if err := db.First(&course, "id = ?", courseID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "课程不存在"})
+		return
+	}
+	if !canManageCourse(db, courseID, currentUser(c).ID) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权删除该课程"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Experiment{}).Where("course_id = ?", courseID).Update("course_id", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Group{}).Where("course_id = ?", courseID).Update("course_id", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Notification{}).Where("course_id = ?", courseID).Update("course_id", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&course).Association("Teachers").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&course).Association("Students").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&course).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除课程失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "课程删除成功",
+	})
+}
+
+// AddCourseStudents 为课程添加选课学生
+func AddCourseStudents(c *gin.Context) {
+	var req struct {
+		StudentIDs []string `json:"student_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	courseID := c.Param("course_id")
+
+	var course models.Course
+	if err := db.First(&course, "id = ?", courseID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "课程不存在"})
+		return
+	}
+	if !canManageCourse(db, courseID, currentUser(c).ID) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权修改该课程"})
+		return
+	}
+	students, err := findUsersByRole(db, req.StudentIDs, "student")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	if err := db.Model(&course).Association("Students").Append(students); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "添加学生失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"course_id":   course.ID,
+			"student_ids": userIDStrings(students),
+		},
+		"message": "添加学生成功",
+	})
+}
+
+// RemoveCourseStudent 将学生移出课程
+func RemoveCourseStudent(c *gin.Context) {
+	db := global.DB
+	courseID := c.Param("course_id")
+	studentID := common.StrToUint(c.Param("student_id"))
+
+	var course models.Course
+	if err := db.First(&course, "id = ?", courseID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "课程不存在"})
+		return
+	}
+	if !canManageCourse(db, courseID, currentUser(c).ID) {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权修改该课程"})
+		return
+	}
+	if err := db.Model(&course).Association("Students").
+		Delete(&models.User{Model: gorm.Model{ID: studentID}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "移除学生失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除学生成功",
+	})
+}
+
+// GetCourses_Student 获取学生选修的课程列表
+func GetCourses_Student(c *gin.Context) {
+	db := common.GetDB()
+	enrolledCourses := db.Table("course_students").
+		Select("course_id").
+		Where("user_id = ?", currentUser(c).ID)
+
+	var courses []models.Course
+	if err := db.Where("id IN (?)", enrolledCourses).
+		Order("created_at DESC").
+		Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "数据库查询失败",
+		})
+		return
+	}
+
+	response := make([]gin.H, len(courses))
+	for i, course := range courses {
+		response[i] = gin.H{
+			"course_id":   course.ID,
+			"name":        course.Name,
+			"code":        course.Code,
+			"term":        course.Term,
+			"description": course.Description,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   response,
+	})
+}
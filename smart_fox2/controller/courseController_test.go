@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"lh/global"
+	"lh/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestDBCourse(t *testing.T) {
+	setupTestDBTeacher(t)
+	if err := global.DB.AutoMigrate(&models.Course{}, &models.Group{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+}
+
+func TestCreateCourse(t *testing.T) {
+	setupTestDBCourse(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+
+	body := `{"name":"操作系统实验","code":"CS301","term":"2025-1","student_ids":["` + strconv.Itoa(int(stu.ID)) + `"]}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Request = httptest.NewRequest("POST", "/courses", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateCourse(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Data struct {
+			CourseID   string   `json:"course_id"`
+			TeacherIDs []string `json:"teacher_ids"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, []string{strconv.Itoa(int(teacher.ID))}, resp.Data.TeacherIDs)
+	assert.True(t, canManageCourse(global.DB, resp.Data.CourseID, teacher.ID))
+	assert.True(t, isEnrolledInCourse(global.DB, resp.Data.CourseID, stu.ID))
+}
+
+func TestUpdateCourse_NotTeacher(t *testing.T) {
+	setupTestDBCourse(t)
+	teacher := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+	global.DB.Create(&models.Course{ID: "course1", Name: "课程", Teachers: []models.User{teacher}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", other)
+	c.Params = []gin.Param{{Key: "course_id", Value: "course1"}}
+	c.Request = httptest.NewRequest("PUT", "/courses/course1", bytes.NewBufferString(`{"name":"改名"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	UpdateCourse(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetExperimentsStudent_CourseFilter(t *testing.T) {
+	setupTestDBCourse(t)
+	stu := createTestUser(t, "student")
+	global.DB.Create(&models.Experiment{ID: "exp-c1", Title: "A", CourseID: "course1", Deadline: time.Now().Add(time.Hour), Users: []models.User{stu}})
+	global.DB.Create(&models.Experiment{ID: "exp-c2", Title: "B", CourseID: "course2", Deadline: time.Now().Add(time.Hour), Users: []models.User{stu}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Request = httptest.NewRequest("GET", "/experiments?course_id=course1", nil)
+
+	GetExperiments_Student(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, "exp-c1", resp.Data[0].ExperimentID)
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"errors"
+	"lh/common"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 辅助函数：加载实验并校验教师权限，失败时直接写入响应
+func loadManagedExperiment(c *gin.Context, db *gorm.DB) (models.Experiment, bool) {
+	var experiment models.Experiment
+	if err := db.First(&experiment, "id = ?", c.Param("experiment_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "实验不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		}
+		return experiment, false
+	}
+	if !canManageExperiment(db, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "无权访问该实验"})
+		return experiment, false
+	}
+	return experiment, true
+}
+
+// 辅助函数：判断学生是否被分配了该实验（直接分配或通过分组）
+func isAssignedToExperiment(db *gorm.DB, experimentID string, studentID uint) (bool, error) {
+	experimentIDs, err := getAssignedExperimentIDs(db, studentID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range experimentIDs {
+		if id == experimentID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func extensionResponse(extension models.DeadlineExtension) gin.H {
+	return gin.H{
+		"extension_id":    extension.ID,
+		"experiment_id":   extension.ExperimentID,
+		"student_id":      strconv.FormatUint(uint64(extension.StudentID), 10),
+		"deadline":        formatTimePtr(extension.Deadline),
+		"time_multiplier": extension.TimeMultiplier,
+		"reason":          extension.Reason,
+		"granted_by":      strconv.FormatUint(uint64(extension.GrantedBy), 10),
+		"updated_at":      extension.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// GetExtensions 获取实验的学生个人延期列表
+func GetExtensions(c *gin.Context) {
+	db := common.GetDB()
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+
+	var extensions []models.DeadlineExtension
+	if err := db.Where("experiment_id = ?", experiment.ID).Order("student_id").Find(&extensions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		return
+	}
+	response := make([]gin.H, len(extensions))
+	for i, extension := range extensions {
+		response[i] = extensionResponse(extension)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   response,
+	})
+}
+
+// SetExtension 为学生设置个人截止时间或限时作答倍数，已存在时覆盖
+func SetExtension(c *gin.Context) {
+	var req struct {
+		Deadline       *time.Time `json:"deadline"`
+		TimeMultiplier float64    `json:"time_multiplier" binding:"omitempty,gte=1"`
+		Reason         string     `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	if req.Deadline == nil && req.TimeMultiplier == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "deadline 和 time_multiplier 至少提供一项"})
+		return
+	}
+	db := global.DB
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	if req.Deadline != nil && !req.Deadline.After(experiment.Deadline) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "个人截止时间必须晚于实验截止时间"})
+		return
+	}
+	studentID := common.StrToUint(c.Param("student_id"))
+	assigned, err := isAssignedToExperiment(db, experiment.ID, studentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		return
+	}
+	if !assigned {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "该学生未被分配此实验"})
+		return
+	}
+	if req.TimeMultiplier == 0 {
+		req.TimeMultiplier = 1
+	}
+
+	var extension models.DeadlineExtension
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("experiment_id = ? AND student_id = ?", experiment.ID, studentID).First(&extension)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return result.Error
+		}
+		extension.ExperimentID = experiment.ID
+		extension.StudentID = studentID
+		extension.Deadline = req.Deadline
+		extension.TimeMultiplier = req.TimeMultiplier
+		extension.Reason = req.Reason
+		extension.GrantedBy = currentUser(c).ID
+		if err := tx.Save(&extension).Error; err != nil {
+			return err
+		}
+		return recomputeAttemptEndsAt(tx, experiment, studentID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存延期失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   extensionResponse(extension),
+	})
+}
+
+// recomputeAttemptEndsAt 正在限时作答的学生按当前的延期设置重新计算个人截止时间
+func recomputeAttemptEndsAt(tx *gorm.DB, experiment models.Experiment, studentID uint) error {
+	var submission models.ExperimentSubmission
+	if err := tx.Where("experiment_id = ? AND student_id = ? AND status = ? AND started_at IS NOT NULL",
+		experiment.ID, studentID, "in_progress").First(&submission).Error; err != nil {
+		return nil
+	}
+	effective := experiment
+	applyStudentExtension(tx, &effective, studentID)
+	endsAt := attemptEndsAt(effective, *submission.StartedAt)
+	return tx.Model(&submission).Update("ends_at", endsAt).Error
+}
+
+// DeleteExtension 取消学生的个人延期
+func DeleteExtension(c *gin.Context) {
+	db := global.DB
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	studentID := common.StrToUint(c.Param("student_id"))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("experiment_id = ? AND student_id = ?", experiment.ID, studentID).
+			Delete(&models.DeadlineExtension{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 限时倍数随延期取消，正在作答的学生恢复按实验时长计算截止时间
+		return recomputeAttemptEndsAt(tx, experiment, studentID)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "延期记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "删除延期失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "延期已取消",
+	})
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetExtension_StudentCanSubmitAfterDeadline(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	other := createTestUser(t, "student")
+	exp := models.Experiment{
+		ID:         "exp-ext",
+		Title:      "延期实验",
+		Permission: 0,
+		Deadline:   time.Now().Add(-time.Hour),
+		TeacherID:  teacher.ID,
+		Users:      []models.User{stu, other},
+		Questions: []models.Question{
+			{ID: "q-ext", Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5},
+		},
+	}
+	global.DB.Create(&exp)
+
+	extended := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}, {Key: "student_id", Value: strconv.Itoa(int(stu.ID))}}
+	c.Request = httptest.NewRequest("PUT", "/extensions", bytes.NewBufferString(`{"deadline":"`+extended+`","reason":"病假"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SetExtension(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	submit := func(user models.User) int {
+		body := `{"answers":[{"question_id":"q-ext","type":"blank","answer":"2"}]}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", user)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+		c.Request = httptest.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		SubmitExperiment(c)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, submit(stu))
+	assert.Equal(t, http.StatusBadRequest, submit(other))
+
+	// 延期学生的实验不应出现在已过期列表中
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Request = httptest.NewRequest("GET", "/experiments?status=expired", nil)
+	GetExperiments_Student(c)
+	var resp struct {
+		Data []interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Empty(t, resp.Data)
+}
+
+func TestSetExtension_NotAssigned(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	global.DB.Create(&models.Experiment{ID: "exp-ext2", Title: "实验", Deadline: time.Now().Add(time.Hour), TeacherID: teacher.ID})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: "exp-ext2"}, {Key: "student_id", Value: strconv.Itoa(int(stu.ID))}}
+	c.Request = httptest.NewRequest("PUT", "/extensions", bytes.NewBufferString(`{"time_multiplier":1.5}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SetExtension(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// 取消延期后，正在作答的学生恢复按实验时长计算截止时间
+func TestDeleteExtension_RecomputesEndsAt(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	global.DB.Create(&models.Experiment{ID: "exp-ext3", Title: "限时实验", DurationMinutes: 60,
+		Deadline: time.Now().Add(24 * time.Hour), TeacherID: teacher.ID, Users: []models.User{stu}})
+	global.DB.Create(&models.DeadlineExtension{ExperimentID: "exp-ext3", StudentID: stu.ID, TimeMultiplier: 2})
+	startedAt := time.Now().Add(-10 * time.Minute)
+	extendedEnd := startedAt.Add(120 * time.Minute)
+	global.DB.Create(&models.ExperimentSubmission{ID: "sub-ext3", ExperimentID: "exp-ext3", StudentID: stu.ID,
+		Status: "in_progress", StartedAt: &startedAt, EndsAt: &extendedEnd})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: "exp-ext3"}, {Key: "student_id", Value: strconv.Itoa(int(stu.ID))}}
+	c.Request = httptest.NewRequest("DELETE", "/extensions", nil)
+	DeleteExtension(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var submission models.ExperimentSubmission
+	global.DB.First(&submission, "id = ?", "sub-ext3")
+	if assert.NotNil(t, submission.EndsAt) {
+		assert.WithinDuration(t, startedAt.Add(60*time.Minute), *submission.EndsAt, time.Second)
+	}
+
+	// 再次取消时延期记录已不存在
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: "exp-ext3"}, {Key: "student_id", Value: strconv.Itoa(int(stu.ID))}}
+	c.Request = httptest.NewRequest("DELETE", "/extensions", nil)
+	DeleteExtension(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
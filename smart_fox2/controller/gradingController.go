@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"errors"
+	"lh/common"
+	"lh/global"
+	"lh/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// finishedStatuses 已提交的提交记录状态，待批阅和已批阅的提交同样计入提交次数
+var finishedStatuses = []string{"submitted", "pending_review", "graded"}
+
+// submissionFinished 判断提交记录是否已提交
+func submissionFinished(status string) bool {
+	for _, s := range finishedStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewStatusFor 主观题和设置了评分细则的题目需要人工批阅，提交后标记为待批阅
+func reviewStatusFor(question models.Question) string {
+	if question.Type == "essay" || question.Rubric != "" {
+		return "pending"
+	}
+	return ""
+}
+
+// submittedStatus 根据是否有待批阅的题目决定提交记录的状态
+func submittedStatus(pendingReview bool) string {
+	if pendingReview {
+		return "pending_review"
+	}
+	return "submitted"
+}
+
+// recomputeSubmissionScore 按各题当前得分重新计算提交总分和迟交扣分，待批阅题目全部批完后标记为已批阅
+func recomputeSubmissionScore(tx *gorm.DB, submission *models.ExperimentSubmission) error {
+	var answers []models.QuestionSubmission
+	if err := tx.Where("submission_id = ?", submission.ID).Find(&answers).Error; err != nil {
+		return err
+	}
+	rawScore, pending := 0, false
+	for _, answer := range answers {
+		rawScore += answer.Score
+		if answer.ReviewStatus == "pending" {
+			pending = true
+		}
+	}
+	var experiment models.Experiment
+	if err := tx.First(&experiment, "id = ?", submission.ExperimentID).Error; err != nil {
+		return err
+	}
+	applyStudentExtension(tx, &experiment, submission.StudentID)
+	submission.RawScore = rawScore
+	submission.TotalScore, submission.LatePenalty = applyLatePenalty(experiment, rawScore, submission.SubmittedAt)
+	if pending {
+		submission.Status = "pending_review"
+	} else if submission.Status == "pending_review" {
+		submission.Status = "graded"
+	}
+	return tx.Model(&models.ExperimentSubmission{}).Where("id = ?", submission.ID).
+		Updates(map[string]interface{}{
+			"raw_score":    submission.RawScore,
+			"late_penalty": submission.LatePenalty,
+			"total_score":  submission.TotalScore,
+			"status":       submission.Status,
+		}).Error
+}
+
+func reviewAnswerResponse(answer models.QuestionSubmission) gin.H {
+	submission := answer.ExperimentSubmission
+	data := gin.H{
+		"answer_id":        answer.ID,
+		"submission_id":    answer.SubmissionID,
+		"student_id":       strconv.FormatUint(uint64(submission.StudentID), 10),
+		"student_name":     submission.Student.Name,
+		"attempt_number":   submission.Attempt,
+		"submitted_at":     submission.SubmittedAt.Format(time.RFC3339),
+		"question_id":      answer.QuestionID,
+		"question_content": answer.Question.Content,
+		"answer":           answer.Answer,
+		"score":            answer.Score,
+		"perfect_score":    answer.PerfectScore,
+		"feedback":         answer.Feedback,
+		"review_status":    answer.ReviewStatus,
+		"comment":          answer.Comment,
+	}
+	if rubric := parseRubric(answer.Question); rubric != nil {
+		data["rubric"] = rubric
+		data["rubric_grades"] = parseRubricGrades(answer)
+	}
+	if answer.GradedAt != nil {
+		data["graded_at"] = answer.GradedAt.Format(time.RFC3339)
+	}
+	return data
+}
+
+// 辅助函数：加载教师所管理实验中某次已提交作答的单题答案，失败时直接写入响应
+func loadSubmittedAnswer(c *gin.Context, db *gorm.DB) (models.QuestionSubmission, bool) {
+	var answer models.QuestionSubmission
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return answer, false
+	}
+	if err := db.Preload("ExperimentSubmission").First(&answer, "id = ?", c.Param("answer_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "答案不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		}
+		return answer, false
+	}
+	if answer.ExperimentSubmission.ExperimentID != experiment.ID {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "答案不存在"})
+		return answer, false
+	}
+	if !submissionFinished(answer.ExperimentSubmission.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "学生尚未提交该作答"})
+		return answer, false
+	}
+	return answer, true
+}
+
+// GetReviewAnswers 获取实验中待人工批阅的主观题答案，status=graded 获取已批阅的答案，status=all 获取全部
+func GetReviewAnswers(c *gin.Context) {
+	db := common.GetDB()
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	if err := finalizeExpiredAttempts(db, experiment.ID); err != nil {
+		log.Printf("自动提交到时作答失败: %v", err)
+	}
+
+	query := db.Model(&models.QuestionSubmission{}).
+		Joins("JOIN experiment_submissions ON experiment_submissions.id = question_submissions.submission_id").
+		Where("experiment_submissions.experiment_id = ? AND experiment_submissions.status IN ?", experiment.ID, finishedStatuses)
+	switch status := c.DefaultQuery("status", "pending"); status {
+	case "pending", "graded":
+		query = query.Where("question_submissions.review_status = ?", status)
+	case "all":
+		query = query.Where("question_submissions.review_status <> ''")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "status 参数只能是 pending、graded 或 all"})
+		return
+	}
+	if questionID := c.Query("question_id"); questionID != "" {
+		query = query.Where("question_submissions.question_id = ?", questionID)
+	}
+
+	var answers []models.QuestionSubmission
+	if err := query.Preload("Question").Preload("ExperimentSubmission.Student").
+		Order("experiment_submissions.submitted_at ASC").
+		Find(&answers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		return
+	}
+	response := make([]gin.H, len(answers))
+	for i, answer := range answers {
+		response[i] = reviewAnswerResponse(answer)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   response,
+		"total":  len(answers),
+	})
+}
+
+// GradeAnswer 教师为主观题答案打分，该次提交的最后一道待批阅题目批完后重新计算总分
+func GradeAnswer(c *gin.Context) {
+	var req struct {
+		Score    *int   `json:"score" binding:"required,gte=0"`
+		Feedback string `json:"feedback"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	answer, ok := loadSubmittedAnswer(c, db)
+	if !ok {
+		return
+	}
+	submission := answer.ExperimentSubmission
+	if answer.ReviewStatus == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "该答案不需要人工批阅"})
+		return
+	}
+	if *req.Score > answer.PerfectScore {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "得分不能超过题目满分"})
+		return
+	}
+
+	graderID := currentUser(c).ID
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.QuestionSubmission{}).Where("id = ?", answer.ID).
+			Updates(map[string]interface{}{
+				"score":         *req.Score,
+				"feedback":      req.Feedback,
+				"review_status": "graded",
+				"graded_by":     graderID,
+				"graded_at":     now,
+			}).Error; err != nil {
+			return err
+		}
+		return recomputeSubmissionScore(tx, &submission)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存评分失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "评分已保存",
+		"data": gin.H{
+			"answer_id":         answer.ID,
+			"score":             *req.Score,
+			"submission_id":     submission.ID,
+			"submission_status": submission.Status,
+			"raw_score":         submission.RawScore,
+			"late_penalty":      submission.LatePenalty,
+			"total_score":       submission.TotalScore,
+		},
+	})
+}
+
+// OverrideAnswerScore 教师修改单题得分并填写原因和评语，首次改分时保留原自动评分用于审计
+func OverrideAnswerScore(c *gin.Context) {
+	var req struct {
+		Score   *int    `json:"score" binding:"required,gte=0"`
+		Reason  string  `json:"reason" binding:"required"`
+		Comment *string `json:"comment"` // 不传时保留原有评语
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	answer, ok := loadSubmittedAnswer(c, db)
+	if !ok {
+		return
+	}
+	if *req.Score > answer.PerfectScore {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "得分不能超过题目满分"})
+		return
+	}
+
+	submission := answer.ExperimentSubmission
+	now := time.Now()
+	updates := map[string]interface{}{
+		"score":           *req.Score,
+		"override_reason": req.Reason,
+		"overridden_by":   currentUser(c).ID,
+		"overridden_at":   now,
+	}
+	comment := answer.Comment
+	if req.Comment != nil {
+		comment = *req.Comment
+		updates["comment"] = comment
+	}
+	if answer.AutoScore == nil {
+		updates["auto_score"] = answer.Score
+	}
+	// 改分视为完成人工批阅
+	if answer.ReviewStatus == "pending" {
+		updates["review_status"] = "graded"
+		updates["graded_by"] = currentUser(c).ID
+		updates["graded_at"] = now
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.QuestionSubmission{}).Where("id = ?", answer.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recomputeSubmissionScore(tx, &submission)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存改分失败"})
+		return
+	}
+	autoScore := answer.Score
+	if answer.AutoScore != nil {
+		autoScore = *answer.AutoScore
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "得分已修改",
+		"data": gin.H{
+			"answer_id":         answer.ID,
+			"score":             *req.Score,
+			"auto_score":        autoScore,
+			"reason":            req.Reason,
+			"comment":           comment,
+			"submission_id":     submission.ID,
+			"submission_status": submission.Status,
+			"raw_score":         submission.RawScore,
+			"late_penalty":      submission.LatePenalty,
+			"total_score":       submission.TotalScore,
+		},
+	})
+}
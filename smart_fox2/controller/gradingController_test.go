@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGradeAnswer_EssayWorkflow(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	exp := models.Experiment{
+		ID:         "exp-essay",
+		Title:      "实验报告",
+		Permission: 1,
+		Deadline:   time.Now().Add(time.Hour),
+		TeacherID:  teacher.ID,
+		Users:      []models.User{stu},
+		Questions: []models.Question{
+			{ID: "q-choice", Type: "choice", Content: "1+1", Options: `["1","2"]`, CorrectAnswer: "2", Score: 2},
+			{ID: "q-essay1", Type: "essay", Content: "实验结论", Score: 10},
+			{ID: "q-essay2", Type: "essay", Content: "误差分析", Score: 8},
+		},
+	}
+	global.DB.Create(&exp)
+
+	body := `{"answers":[` +
+		`{"question_id":"q-choice","type":"choice","answer":"2"},` +
+		`{"question_id":"q-essay1","type":"essay","answer":"欧姆定律成立"},` +
+		`{"question_id":"q-essay2","type":"essay","answer":"读数误差"}]}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request = httptest.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"submission_status":"pending_review"`)
+	assert.Contains(t, w.Body.String(), "Pending manual grading")
+
+	listReviews := func() []struct {
+		AnswerID   string `json:"answer_id"`
+		QuestionID string `json:"question_id"`
+		Answer     string `json:"answer"`
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+		c.Request = httptest.NewRequest("GET", "/reviews", nil)
+		GetReviewAnswers(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []struct {
+				AnswerID   string `json:"answer_id"`
+				QuestionID string `json:"question_id"`
+				Answer     string `json:"answer"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data
+	}
+	grade := func(answerID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}, {Key: "answer_id", Value: answerID}}
+		c.Request = httptest.NewRequest("PUT", "/reviews", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		GradeAnswer(c)
+		return w
+	}
+
+	pending := listReviews()
+	assert.Len(t, pending, 2)
+
+	// 超过满分的评分被拒绝
+	assert.Equal(t, http.StatusBadRequest, grade(pending[0].AnswerID, `{"score":11}`).Code)
+
+	w = grade(pending[0].AnswerID, `{"score":9,"feedback":"结论清晰"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"submission_status":"pending_review"`)
+	assert.Len(t, listReviews(), 1)
+
+	w = grade(pending[1].AnswerID, `{"score":6}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"submission_status":"graded"`)
+	assert.Empty(t, listReviews())
+
+	var submission models.ExperimentSubmission
+	global.DB.Where("experiment_id = ? AND student_id = ?", exp.ID, stu.ID).First(&submission)
+	assert.Equal(t, "graded", submission.Status)
+	assert.Equal(t, 17, submission.TotalScore)
+
+	// 已批阅的提交仍计入提交次数
+	used, _ := countSubmittedAttempts(global.DB, exp.ID, stu.ID)
+	assert.Equal(t, 1, used)
+}
+
+func TestGradeAnswer_AutoGradedAnswerRejected(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	global.DB.Create(&models.Experiment{ID: "exp-auto", Title: "实验", Deadline: time.Now().Add(time.Hour), TeacherID: teacher.ID})
+	global.DB.Create(&models.ExperimentSubmission{ID: "sub-auto", ExperimentID: "exp-auto", StudentID: stu.ID, Status: "submitted"})
+	global.DB.Create(&models.QuestionSubmission{ID: "ans-auto", SubmissionID: "sub-auto", QuestionID: "q", Type: "choice", PerfectScore: 5})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: "exp-auto"}, {Key: "answer_id", Value: "ans-auto"}}
+	c.Request = httptest.NewRequest("PUT", "/reviews", bytes.NewBufferString(`{"score":5}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	GradeAnswer(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOverrideAnswerScore(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	global.DB.Create(&models.Experiment{ID: "exp-override", Title: "实验", Permission: 1, Deadline: time.Now().Add(time.Hour), TeacherID: teacher.ID})
+	global.DB.Create(&models.ExperimentSubmission{ID: "sub-override", ExperimentID: "exp-override", StudentID: stu.ID,
+		Status: "submitted", RawScore: 3, TotalScore: 3, Attempt: 1, SubmittedAt: time.Now()})
+	global.DB.Create(&models.QuestionSubmission{ID: "ans-1", SubmissionID: "sub-override", QuestionID: "q1", Type: "code", PerfectScore: 10, Score: 0, Feedback: "Evaluation error: timeout"})
+	global.DB.Create(&models.QuestionSubmission{ID: "ans-2", SubmissionID: "sub-override", QuestionID: "q2", Type: "choice", PerfectScore: 3, Score: 3, Feedback: "Correct"})
+
+	override := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: "exp-override"}, {Key: "answer_id", Value: "ans-1"}}
+		c.Request = httptest.NewRequest("PUT", "/score", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		OverrideAnswerScore(c)
+		return w
+	}
+
+	// 必须填写改分原因
+	assert.Equal(t, http.StatusBadRequest, override(`{"score":8}`).Code)
+	assert.Equal(t, http.StatusBadRequest, override(`{"score":11,"reason":"评测超时"}`).Code)
+
+	w := override(`{"score":8,"reason":"评测服务超时","comment":"代码逻辑正确，边界处理欠缺"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_score":11`)
+
+	// 再次改分时保留最初的自动评分
+	assert.Equal(t, http.StatusOK, override(`{"score":9,"reason":"复核"}`).Code)
+	var answer models.QuestionSubmission
+	global.DB.First(&answer, "id = ?", "ans-1")
+	assert.Equal(t, 9, answer.Score)
+	if assert.NotNil(t, answer.AutoScore) {
+		assert.Equal(t, 0, *answer.AutoScore)
+	}
+	assert.Equal(t, "复核", answer.OverrideReason)
+
+	var submission models.ExperimentSubmission
+	global.DB.First(&submission, "id = ?", "sub-override")
+	assert.Equal(t, 12, submission.TotalScore)
+
+	// 学生在提交记录中看到教师评语
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Request = httptest.NewRequest("GET", "/submissions", nil)
+	GetSubmissions(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []struct {
+			Results []struct {
+				QuestionID string `json:"question_id"`
+				Comment    string `json:"comment"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if assert.Len(t, resp.Data, 1) {
+		comments := map[string]string{}
+		for _, r := range resp.Data[0].Results {
+			comments[r.QuestionID] = r.Comment
+		}
+		assert.Equal(t, "代码逻辑正确，边界处理欠缺", comments["q1"])
+		assert.Equal(t, "", comments["q2"])
+	}
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"lh/models"
+	"strings"
+)
+
+// harnessPlaceholder 测试框架中插入学生代码的位置，框架中没有占位符时学生代码放在框架之前
+const harnessPlaceholder = "{{student_code}}"
+
+// parseLanguageCode 读取按语言存储的代码，如起始代码和测试框架，未设置时返回 nil
+func parseLanguageCode(data string) map[string]string {
+	var code map[string]string
+	if data == "" || json.Unmarshal([]byte(data), &code) != nil {
+		return nil
+	}
+	return code
+}
+
+// languageCodeJSON 序列化按语言存储的代码，忽略空白内容，全部为空时清除
+func languageCodeJSON(code map[string]string) string {
+	filtered := make(map[string]string, len(code))
+	for language, source := range code {
+		if strings.TrimSpace(source) != "" {
+			filtered[language] = source
+		}
+	}
+	if len(filtered) == 0 {
+		return ""
+	}
+	return toJSONString(filtered)
+}
+
+// assembleSource 函数题将学生实现的函数与隐藏的测试框架拼接成完整程序，普通代码题原样返回
+func assembleSource(question models.Question, code, language string) (string, error) {
+	harnesses := parseLanguageCode(question.Harness)
+	if harnesses == nil {
+		return code, nil
+	}
+	harness, ok := harnesses[language]
+	if !ok {
+		return "", fmt.Errorf("language %s is not supported for this question", language)
+	}
+	if strings.Contains(harness, harnessPlaceholder) {
+		return strings.Replace(harness, harnessPlaceholder, code, 1), nil
+	}
+	return code + "\n" + harness, nil
+}
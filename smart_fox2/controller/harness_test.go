@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssembleSource(t *testing.T) {
+	question := models.Question{Type: "code", Harness: languageCodeJSON(map[string]string{
+		"python": "print(add(*map(int, input().split())))",
+		"cpp":    "#include <iostream>\n{{student_code}}\nint main() { int a, b; std::cin >> a >> b; std::cout << add(a, b); }",
+		"java":   "  ",
+	})}
+
+	source, err := assembleSource(question, "def add(a, b):\n    return a + b", "python")
+	assert.NoError(t, err)
+	assert.Equal(t, "def add(a, b):\n    return a + b\nprint(add(*map(int, input().split())))", source)
+
+	source, err = assembleSource(question, "int add(int a, int b) { return a + b; }", "cpp")
+	assert.NoError(t, err)
+	assert.Equal(t, "#include <iostream>\nint add(int a, int b) { return a + b; }\nint main() { int a, b; std::cin >> a >> b; std::cout << add(a, b); }", source)
+
+	// 空白的测试框架不会保存，该语言不能作答
+	_, err = assembleSource(question, "class Solution {}", "java")
+	assert.Error(t, err)
+
+	// 普通代码题原样评测
+	source, err = assembleSource(models.Question{Type: "code"}, "print(1)", "java")
+	assert.NoError(t, err)
+	assert.Equal(t, "print(1)", source)
+}
+
+func TestGradeAnswer_FunctionQuestion(t *testing.T) {
+	var received EvaluationRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(EvaluationResponse{CaseResults: []EvaluationCaseResult{{Status: statusAccepted}}})
+	}))
+	defer srv.Close()
+	t.Setenv("JUDGE_URL", srv.URL)
+
+	question := models.Question{Type: "code", Score: 4, TestCases: `[{"input":"1 2","expected_output":"3"}]`,
+		Harness: `{"python":"print(add(*map(int, input().split())))"}`}
+	score, _, _ := gradeAnswer(question, submittedAnswer{Type: "code", Code: "def add(a, b):\n    return a + b", Language: "python"})
+	assert.Equal(t, 4, score)
+	assert.Equal(t, "def add(a, b):\n    return a + b\nprint(add(*map(int, input().split())))", received.SourceCode)
+
+	score, feedback, _ := gradeAnswer(question, submittedAnswer{Type: "code", Code: "int add(int a, int b);", Language: "cpp"})
+	assert.Equal(t, 0, score)
+	assert.Equal(t, "Evaluation error: language cpp is not supported for this question", feedback)
+}
+
+func TestGetExperimentDetail_Student_StarterCode(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:         uuid.New().String(),
+		Title:      "Functions",
+		Permission: 1,
+		Deadline:   time.Now().Add(24 * time.Hour),
+		Users:      []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "code", Content: "实现 add", Score: 5,
+				TestCases:   `[{"input":"1 2","expected_output":"3"}]`,
+				StarterCode: `{"python":"def add(a, b):\n    pass"}`,
+				Harness:     `{"python":"print(add(*map(int, input().split())))"}`},
+		},
+	}
+	db.Create(&exp)
+
+	detail := func() map[string]interface{} {
+		c, w := setupTestContext(user)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+		GetExperimentDetail_Student(c)
+		assert.NotContains(t, w.Body.String(), "input().split()", "测试框架不向学生展示")
+		var resp struct {
+			Data struct {
+				Questions []map[string]interface{} `json:"questions"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if assert.Len(t, resp.Data.Questions, 1) {
+			return resp.Data.Questions[0]
+		}
+		return nil
+	}
+	assert.Equal(t, map[string]interface{}{"python": "def add(a, b):\n    pass"}, detail()["starter_code"])
+
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"code","code":"def add(a, b):\n    return a + b","language":"python"}]}`
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/save", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SaveAnswer(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 保存代码后返回学生自己的代码
+	question := detail()
+	assert.Nil(t, question["starter_code"])
+	assert.Equal(t, "def add(a, b):\n    return a + b", question["student_code"])
+}
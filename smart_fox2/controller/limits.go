@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"encoding/json"
+	"lh/models"
+)
+
+// defaultTimeLimit 题目未设置时间限制时使用的默认值（秒）
+const defaultTimeLimit = 2
+
+// ResourceLimit 代码题的时间和内存限制，0 表示沿用题目或评测服务的默认值
+type ResourceLimit struct {
+	TimeLimit     int `json:"time_limit,omitempty" binding:"gte=0,lte=60"`
+	MemoryLimitMB int `json:"memory_limit_mb,omitempty" binding:"gte=0,lte=4096"`
+}
+
+// parseLanguageLimits 读取题目按语言设置的限制，未设置时返回 nil
+func parseLanguageLimits(question models.Question) map[string]ResourceLimit {
+	var limits map[string]ResourceLimit
+	if question.LanguageLimits == "" || json.Unmarshal([]byte(question.LanguageLimits), &limits) != nil {
+		return nil
+	}
+	return limits
+}
+
+// languageLimitsJSON 序列化按语言设置的限制，为空时清除
+func languageLimitsJSON(limits map[string]ResourceLimit) string {
+	if len(limits) == 0 {
+		return ""
+	}
+	return toJSONString(limits)
+}
+
+// resourceLimits 计算某种语言实际使用的时间和内存限制，语言单独设置的值优先于题目的值。
+// 内存限制为 0 时不发送，由评测服务使用默认值
+func resourceLimits(question models.Question, language string) (int, int) {
+	timeLimit, memoryLimit := question.TimeLimit, question.MemoryLimitMB
+	if limit, ok := parseLanguageLimits(question)[language]; ok {
+		if limit.TimeLimit > 0 {
+			timeLimit = limit.TimeLimit
+		}
+		if limit.MemoryLimitMB > 0 {
+			memoryLimit = limit.MemoryLimitMB
+		}
+	}
+	if timeLimit == 0 {
+		timeLimit = defaultTimeLimit
+	}
+	return timeLimit, memoryLimit
+}
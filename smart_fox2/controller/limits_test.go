@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLimits(t *testing.T) {
+	question := models.Question{Type: "code", TimeLimit: 1, MemoryLimitMB: 64,
+		LanguageLimits: `{"java":{"time_limit":3,"memory_limit_mb":256},"python":{"time_limit":5}}`}
+
+	timeLimit, memoryLimit := resourceLimits(question, "cpp")
+	assert.Equal(t, 1, timeLimit)
+	assert.Equal(t, 64, memoryLimit)
+	timeLimit, memoryLimit = resourceLimits(question, "java")
+	assert.Equal(t, 3, timeLimit)
+	assert.Equal(t, 256, memoryLimit)
+	timeLimit, memoryLimit = resourceLimits(question, "python")
+	assert.Equal(t, 5, timeLimit)
+	assert.Equal(t, 64, memoryLimit)
+
+	// 未设置时沿用默认的 2 秒，内存限制交给评测服务
+	timeLimit, memoryLimit = resourceLimits(models.Question{Type: "code"}, "cpp")
+	assert.Equal(t, defaultTimeLimit, timeLimit)
+	assert.Equal(t, 0, memoryLimit)
+}
+
+func TestEvaluateCode_ForwardsLimits(t *testing.T) {
+	var received EvaluationRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(EvaluationResponse{})
+	}))
+	defer srv.Close()
+	t.Setenv("JUDGE_URL", srv.URL)
+
+	question := models.Question{Type: "code", TimeLimit: 1, MemoryLimitMB: 128,
+		TestCases: `[{"input":"1","expected_output":"1"}]`, LanguageLimits: `{"java":{"time_limit":4,"memory_limit_mb":512}}`}
+	_, err := evaluateCode("class Main {}", "java", question)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, received.TimeLimit)
+	assert.Equal(t, 512, received.MemoryLimitMB)
+
+	_, err = evaluateCode("int main() {}", "cpp", question)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, received.TimeLimit)
+	assert.Equal(t, 128, received.MemoryLimitMB)
+}
+
+func TestCreateExperiment_CodeLimits(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	teacher := models.User{Name: "teacher", Role: "teacher"}
+	db.Create(&teacher)
+	create := func(question string) *httptest.ResponseRecorder {
+		body := `{"title":"排序","permission":1,"student_ids":["1"],"deadline":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `",
+			"questions":[` + question + `]}`
+		c, w := setupTestContext(teacher)
+		c.Request, _ = http.NewRequest("POST", "/experiments", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CreateExperiment(c)
+		return w
+	}
+
+	w := create(`{"type":"code","content":"排序","score":10,"test_cases":[{"input":"1","expected_output":"1"}],
+		"language_limits":{"go":{"time_limit":1}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = create(`{"type":"code","content":"排序","score":10,"test_cases":[{"input":"1","expected_output":"1"}],
+		"time_limit":1,"memory_limit_mb":64,"language_limits":{"java":{"time_limit":3,"memory_limit_mb":256}}}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var question models.Question
+	db.First(&question, "type = ?", "code")
+	assert.Equal(t, 1, question.TimeLimit)
+	assert.Equal(t, 64, question.MemoryLimitMB)
+	assert.Equal(t, ResourceLimit{TimeLimit: 3, MemoryLimitMB: 256}, parseLanguageLimits(question)["java"])
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"lh/common"
+	"lh/models"
+	"sort"
+	"strings"
+)
+
+// 多选题计分方式
+const (
+	scoringAllOrNothing = "all_or_nothing" // 全部选对才得分
+	scoringProportional = "proportional"   // 少选按选对比例得分，错选不得分
+	scoringPenalty      = "penalty"        // 按选对比例得分，每错选一项扣除一项的分值，最低 0 分
+)
+
+// parseChoiceList 解析多选题答案，支持 JSON 数组或逗号分隔的选项
+func parseChoiceList(answer string) []string {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil
+	}
+	var list []string
+	if strings.HasPrefix(answer, "[") {
+		if err := json.Unmarshal([]byte(answer), &list); err == nil {
+			return list
+		}
+	}
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// canonicalMultiChoiceAnswer 把多选答案逐项换算为原始选项文本，去重后按原始选项顺序存为 JSON 数组
+func canonicalMultiChoiceAnswer(question models.Question, answer string, seed int64, shuffled bool) string {
+	options := common.ParseJSONArray(question.Options)
+	position := make(map[string]int, len(options))
+	for i, option := range options {
+		position[option] = i
+	}
+	seen := make(map[string]bool)
+	selected := []string{}
+	for _, item := range parseChoiceList(answer) {
+		item = canonicalChoiceAnswer(question, item, seed, shuffled)
+		if !seen[item] {
+			seen[item] = true
+			selected = append(selected, item)
+		}
+	}
+	// 不在选项中的内容排在最后，评分时按错选处理
+	sort.SliceStable(selected, func(i, j int) bool {
+		pi, ok := position[selected[i]]
+		if !ok {
+			pi = len(options)
+		}
+		pj, ok := position[selected[j]]
+		if !ok {
+			pj = len(options)
+		}
+		return pi < pj
+	})
+	return toJSONString(selected)
+}
+
+// multiChoiceCorrectAnswer 校验教师给出的正确选项并换算为存储格式，正确选项可以是选项文本或选项字母
+func multiChoiceCorrectAnswer(options []string, answers []string) (string, error) {
+	if len(answers) == 0 {
+		return "", fmt.Errorf("多选题至少需要一个正确选项")
+	}
+	question := models.Question{Options: toJSONString(options)}
+	for _, answer := range answers {
+		found := false
+		canonical := canonicalChoiceAnswer(question, answer, 0, false)
+		for _, option := range options {
+			if option == canonical {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("正确答案 %s 不在选项中", answer)
+		}
+	}
+	return canonicalMultiChoiceAnswer(question, toJSONString(answers), 0, false), nil
+}
+
+// multiChoiceScore 按题目的计分方式为多选题评分
+func multiChoiceScore(question models.Question, answer string) (int, string) {
+	correct := make(map[string]bool)
+	for _, item := range parseChoiceList(canonicalMultiChoiceAnswer(question, question.CorrectAnswer, 0, false)) {
+		correct[item] = true
+	}
+	if len(correct) == 0 {
+		return 0, "Incorrect"
+	}
+	hits, wrong := 0, 0
+	for _, item := range parseChoiceList(answer) {
+		if correct[item] {
+			hits++
+		} else {
+			wrong++
+		}
+	}
+
+	ratio := 0.0
+	switch question.ScoringMode {
+	case scoringProportional:
+		if wrong == 0 {
+			ratio = float64(hits) / float64(len(correct))
+		}
+	case scoringPenalty:
+		if hits > wrong {
+			ratio = float64(hits-wrong) / float64(len(correct))
+		}
+	default:
+		if hits == len(correct) && wrong == 0 {
+			ratio = 1
+		}
+	}
+	score := int(float64(question.Score) * ratio)
+	switch {
+	case ratio == 1:
+		return score, "Correct"
+	case ratio > 0:
+		return score, fmt.Sprintf("Partially correct (%d/%d correct, %d wrong)", hits, len(correct), wrong)
+	default:
+		return score, "Incorrect"
+	}
+}
+
+// multiChoiceScoringMode 返回多选题实际使用的计分方式，未设置时为全对才得分
+func multiChoiceScoringMode(question models.Question) string {
+	if question.ScoringMode == "" {
+		return scoringAllOrNothing
+	}
+	return question.ScoringMode
+}
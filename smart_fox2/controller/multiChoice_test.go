@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"bytes"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiChoiceCorrectAnswer(t *testing.T) {
+	options := []string{"红", "绿", "蓝", "黄"}
+
+	answer, err := multiChoiceCorrectAnswer(options, []string{"C", "红", "A"})
+	assert.NoError(t, err)
+	assert.Equal(t, `["红","蓝"]`, answer)
+
+	_, err = multiChoiceCorrectAnswer(options, []string{"紫"})
+	assert.Error(t, err)
+	_, err = multiChoiceCorrectAnswer(options, nil)
+	assert.Error(t, err)
+}
+
+func TestMultiChoiceScore(t *testing.T) {
+	question := models.Question{
+		ID:            "q1",
+		Type:          "multi_choice",
+		Options:       `["A1","B1","C1","D1"]`,
+		CorrectAnswer: `["A1","B1","C1"]`,
+		Score:         6,
+	}
+	cases := []struct {
+		mode     string
+		answer   string
+		expected int
+	}{
+		{"", `["A1","B1","C1"]`, 6},
+		{"", `["A1","B1"]`, 0},
+		{scoringAllOrNothing, `["A1","B1","C1","D1"]`, 0},
+		{scoringProportional, `["A1","B1"]`, 4},
+		{scoringProportional, `["A1","B1","D1"]`, 0},
+		{scoringPenalty, `["A1","B1","D1"]`, 2},
+		{scoringPenalty, `["A1","D1"]`, 0},
+		{scoringPenalty, `[]`, 0},
+	}
+	for _, tc := range cases {
+		question.ScoringMode = tc.mode
+		score, _ := multiChoiceScore(question, tc.answer)
+		assert.Equal(t, tc.expected, score, "mode=%q answer=%s", tc.mode, tc.answer)
+	}
+
+	question.ScoringMode = scoringProportional
+	_, feedback := multiChoiceScore(question, `["A1"]`)
+	assert.True(t, strings.HasPrefix(feedback, "Partially correct"), feedback)
+}
+
+func TestSubmitExperiment_MultiChoice(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:         uuid.New().String(),
+		Title:      "Multi",
+		Permission: 1,
+		Deadline:   time.Now().Add(24 * time.Hour),
+		Users:      []models.User{user},
+		Questions: []models.Question{
+			{
+				ID:            uuid.New().String(),
+				Type:          "multi_choice",
+				Content:       "哪些是质数",
+				Options:       `["2","3","4","5"]`,
+				CorrectAnswer: `["2","3","5"]`,
+				ScoringMode:   scoringProportional,
+				Score:         9,
+			},
+		},
+	}
+	db.Create(&exp)
+
+	// 用选项字母作答，少选一项
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"multi_choice","answer":"A,B"}]}`
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"total_score":"6/9"`), w.Body.String())
+
+	var saved models.QuestionSubmission
+	db.Where("question_id = ?", exp.Questions[0].ID).First(&saved)
+	assert.Equal(t, `["2","3"]`, saved.Answer)
+}
+
+func TestCreateExperiment_MultiChoiceValidation(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	teacher := models.User{Name: "teacher", Role: "teacher"}
+	db.Create(&teacher)
+	body := `{"title":"多选","permission":1,"student_ids":["1"],"deadline":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `",
+		"questions":[{"type":"multi_choice","content":"q","options":["a","b"],"correct_answers":["c"],"score":2}]}`
+	c, w := setupTestContext(teacher)
+	c.Request, _ = http.NewRequest("POST", "/experiments", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	CreateExperiment(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "不在选项中")
+}
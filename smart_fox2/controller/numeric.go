@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"errors"
+	"lh/models"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 数值题误差类型
+const (
+	toleranceAbsolute = "absolute"
+	toleranceRelative = "relative"
+)
+
+// parseNumericAnswer 解析数值答案，去掉首尾空白和可选的单位后再转换为数字
+func parseNumericAnswer(answer, unit string) (float64, error) {
+	answer = strings.TrimSpace(answer)
+	if unit != "" && strings.HasSuffix(strings.ToLower(answer), strings.ToLower(unit)) {
+		answer = strings.TrimSpace(answer[:len(answer)-len(unit)])
+	}
+	return strconv.ParseFloat(answer, 64)
+}
+
+// numericAnswerCorrect 判断数值答案是否在允许误差范围内，未设置误差时要求数值相等
+func numericAnswerCorrect(question models.Question, answer string) bool {
+	expected, err := parseNumericAnswer(question.CorrectAnswer, question.Unit)
+	if err != nil {
+		return false
+	}
+	actual, err := parseNumericAnswer(answer, question.Unit)
+	if err != nil {
+		return false
+	}
+	allowed := question.Tolerance
+	if question.ToleranceType == toleranceRelative {
+		allowed = question.Tolerance * math.Abs(expected)
+	}
+	// 留出浮点运算误差
+	return math.Abs(actual-expected) <= allowed+1e-9
+}
+
+// parseTrueFalse 解析判断题答案，支持 true/false、T/F、对/错 等常见写法
+func parseTrueFalse(answer string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "true", "t", "yes", "y", "1", "对", "正确", "是", "√":
+		return true, true
+	case "false", "f", "no", "n", "0", "错", "错误", "否", "×":
+		return false, true
+	}
+	return false, false
+}
+
+// canonicalTrueFalseAnswer 把判断题答案统一为 true 或 false，无法识别时原样返回
+func canonicalTrueFalseAnswer(answer string) string {
+	value, ok := parseTrueFalse(answer)
+	if !ok {
+		return answer
+	}
+	return strconv.FormatBool(value)
+}
+
+// objectiveCorrectAnswer 校验数值题和判断题的正确答案并换算为存储格式，其他题型原样返回
+func objectiveCorrectAnswer(questionType, answer, unit string) (string, error) {
+	switch questionType {
+	case "numeric":
+		if _, err := parseNumericAnswer(answer, unit); err != nil {
+			return "", errors.New("数值题的正确答案必须是数字: " + answer)
+		}
+		return strings.TrimSpace(answer), nil
+	case "true_false":
+		if _, ok := parseTrueFalse(answer); !ok {
+			return "", errors.New("判断题的正确答案必须是 true 或 false: " + answer)
+		}
+		return canonicalTrueFalseAnswer(answer), nil
+	}
+	return answer, nil
+}
+
+// numericToleranceType 返回数值题实际使用的误差类型，未设置时为绝对误差
+func numericToleranceType(question models.Question) string {
+	if question.ToleranceType == "" {
+		return toleranceAbsolute
+	}
+	return question.ToleranceType
+}
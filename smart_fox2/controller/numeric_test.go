@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"bytes"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericAnswerCorrect(t *testing.T) {
+	question := models.Question{Type: "numeric", CorrectAnswer: "100", Unit: "Ω"}
+
+	assert.True(t, numericAnswerCorrect(question, "100"))
+	assert.True(t, numericAnswerCorrect(question, " 100.0 Ω"))
+	assert.False(t, numericAnswerCorrect(question, "100.5"))
+	assert.False(t, numericAnswerCorrect(question, "一百"))
+
+	question.Tolerance = 0.5
+	assert.True(t, numericAnswerCorrect(question, "100.5"))
+	assert.False(t, numericAnswerCorrect(question, "99.4Ω"))
+
+	question.Tolerance = 0.05
+	question.ToleranceType = toleranceRelative
+	assert.True(t, numericAnswerCorrect(question, "95"))
+	assert.True(t, numericAnswerCorrect(question, "1.05e2"))
+	assert.False(t, numericAnswerCorrect(question, "94.9"))
+}
+
+func TestObjectiveCorrectAnswer(t *testing.T) {
+	answer, err := objectiveCorrectAnswer("true_false", "对", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", answer)
+
+	_, err = objectiveCorrectAnswer("true_false", "maybe", "")
+	assert.Error(t, err)
+
+	answer, err = objectiveCorrectAnswer("numeric", " 4.7 kΩ", "kΩ")
+	assert.NoError(t, err)
+	assert.Equal(t, "4.7 kΩ", answer)
+
+	_, err = objectiveCorrectAnswer("numeric", "abc", "")
+	assert.Error(t, err)
+}
+
+func TestSubmitExperiment_NumericAndTrueFalse(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:         uuid.New().String(),
+		Title:      "Physics",
+		Permission: 1,
+		Deadline:   time.Now().Add(24 * time.Hour),
+		Users:      []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "numeric", Content: "电阻", CorrectAnswer: "220", Tolerance: 0.01, ToleranceType: toleranceRelative, Unit: "Ω", Score: 4},
+			{ID: uuid.New().String(), Type: "true_false", Content: "欧姆定律适用于半导体", CorrectAnswer: "false", Score: 2},
+		},
+	}
+	db.Create(&exp)
+
+	body := `{"answers":[` +
+		`{"question_id":"` + exp.Questions[0].ID + `","type":"numeric","answer":"218.5Ω"},` +
+		`{"question_id":"` + exp.Questions[1].ID + `","type":"true_false","answer":"错"}]}`
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"total_score":"6/6"`), w.Body.String())
+
+	var saved models.QuestionSubmission
+	db.Where("question_id = ?", exp.Questions[1].ID).First(&saved)
+	assert.Equal(t, "false", saved.Answer)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"encoding/json"
+	"lh/common"
+	"lh/models"
+)
+
+// QuestionInput 题目输入结构体，创建实验、更新实验、导入导出包和题目文件都通过 buildQuestion 生成题目
+type QuestionInput struct {
+	Type              string                   `json:"type" binding:"required,oneof=choice multi_choice blank numeric true_false essay code"`
+	Content           string                   `json:"content" binding:"required"`
+	Options           []string                 `json:"options" binding:"required_if=Type choice required_if=Type multi_choice"`
+	CorrectAnswer     string                   `json:"correct_answer" binding:"required_if=Type choice required_if=Type numeric required_if=Type true_false"`
+	BlankConfig       *BlankConfig             `json:"blank_config"`
+	Rubric            []RubricCriterion        `json:"rubric"`
+	CorrectAnswers    []string                 `json:"correct_answers" binding:"required_if=Type multi_choice"`
+	ScoringMode       string                   `json:"scoring_mode" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
+	Tolerance         float64                  `json:"tolerance" binding:"gte=0"`
+	ToleranceType     string                   `json:"tolerance_type" binding:"omitempty,oneof=absolute relative"`
+	Unit              string                   `json:"unit" binding:"omitempty,max=20"`
+	Score             int                      `json:"score" binding:"required,gt=0"`
+	ImageURL          string                   `json:"image_url" binding:"omitempty"`
+	Explanation       string                   `json:"explanation" binding:"omitempty"`
+	TestCases         []TestCase               `json:"test_cases" binding:"required_if=Type code,dive"`
+	TimeLimit         int                      `json:"time_limit" binding:"gte=0,lte=60"`
+	MemoryLimitMB     int                      `json:"memory_limit_mb" binding:"gte=0,lte=4096"`
+	LanguageLimits    map[string]ResourceLimit `json:"language_limits" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	CompareMode       string                   `json:"compare_mode" binding:"omitempty,oneof=exact ignore_whitespace float checker"`
+	FloatEpsilon      float64                  `json:"float_epsilon" binding:"gte=0"`
+	Checker           *CheckerProgram          `json:"checker"`
+	StarterCode       map[string]string        `json:"starter_code" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	Harness           map[string]string        `json:"harness" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	ReferenceSolution *ReferenceSolution       `json:"reference_solution"`
+	ValidateTestCases string                   `json:"validate_test_cases" binding:"omitempty,oneof=reject flag"` // 用参考答案校验测试用例
+	Pool              string                   `json:"pool" binding:"omitempty,max=50"`
+	Difficulty        string                   `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+}
+
+// buildQuestion 按题型校验输入并生成题目，只设置与题型相关的字段。
+// 代码题在其他校验都通过后才用参考答案评测测试用例，reject 时返回错误，flag 时返回警告
+func buildQuestion(id, experimentID string, q QuestionInput) (models.Question, *TestCaseWarning, error) {
+	question := models.Question{
+		ID:           id,
+		ExperimentID: experimentID,
+		Type:         q.Type,
+		Content:      q.Content,
+		Score:        q.Score,
+		ImageURL:     q.ImageURL,
+		Explanation:  q.Explanation,
+		Pool:         q.Pool,
+		Difficulty:   q.Difficulty,
+	}
+	var err error
+	switch q.Type {
+	case "choice":
+		question.Options = toJSONString(q.Options)
+		question.CorrectAnswer = q.CorrectAnswer
+	case "multi_choice":
+		if question.CorrectAnswer, err = multiChoiceCorrectAnswer(q.Options, q.CorrectAnswers); err != nil {
+			return question, nil, err
+		}
+		question.Options = toJSONString(q.Options)
+		question.ScoringMode = q.ScoringMode
+	case "blank":
+		if question.BlankConfig, question.CorrectAnswer, err = blankConfigJSON(q.Content, q.CorrectAnswer, q.Score, q.BlankConfig); err != nil {
+			return question, nil, err
+		}
+	case "numeric", "true_false":
+		if question.CorrectAnswer, err = objectiveCorrectAnswer(q.Type, q.CorrectAnswer, q.Unit); err != nil {
+			return question, nil, err
+		}
+		if q.Type == "numeric" {
+			question.Tolerance = q.Tolerance
+			question.ToleranceType = q.ToleranceType
+			question.Unit = q.Unit
+		}
+	case "code":
+		if len(q.TestCases) > 0 {
+			question.TestCases = toJSONString(q.TestCases)
+		}
+		question.TimeLimit = q.TimeLimit
+		question.MemoryLimitMB = q.MemoryLimitMB
+		question.LanguageLimits = languageLimitsJSON(q.LanguageLimits)
+		question.FloatEpsilon = q.FloatEpsilon
+		question.StarterCode = languageCodeJSON(q.StarterCode)
+		question.Harness = languageCodeJSON(q.Harness)
+		if q.ReferenceSolution != nil {
+			question.ReferenceSolution = toJSONString(q.ReferenceSolution)
+		}
+		if question.CompareMode, question.Checker, err = checkerJSON(q.CompareMode, q.Checker); err != nil {
+			return question, nil, err
+		}
+	}
+	if question.Rubric, err = rubricJSON(q.Type, q.Score, q.Rubric); err != nil {
+		return question, nil, err
+	}
+	if q.Type != "code" {
+		return question, nil, nil
+	}
+	warning, err := checkReferenceSolution(question, q.ValidateTestCases)
+	return question, warning, err
+}
+
+// questionInput 将已有题目还原为输入结构，更新题目时在此基础上覆盖提交的字段后重新生成
+func questionInput(q models.Question) QuestionInput {
+	input := QuestionInput{
+		Type:              q.Type,
+		Content:           q.Content,
+		Score:             q.Score,
+		CorrectAnswer:     q.CorrectAnswer,
+		ScoringMode:       q.ScoringMode,
+		Tolerance:         q.Tolerance,
+		ToleranceType:     q.ToleranceType,
+		Unit:              q.Unit,
+		Rubric:            parseRubric(q),
+		ImageURL:          q.ImageURL,
+		Explanation:       q.Explanation,
+		Pool:              q.Pool,
+		Difficulty:        q.Difficulty,
+		TimeLimit:         q.TimeLimit,
+		MemoryLimitMB:     q.MemoryLimitMB,
+		LanguageLimits:    parseLanguageLimits(q),
+		CompareMode:       q.CompareMode,
+		FloatEpsilon:      q.FloatEpsilon,
+		Checker:           parseChecker(q),
+		StarterCode:       parseLanguageCode(q.StarterCode),
+		Harness:           parseLanguageCode(q.Harness),
+		ReferenceSolution: parseReferenceSolution(q),
+	}
+	if q.Type == "choice" || q.Type == "multi_choice" {
+		input.Options = common.ParseJSONArray(q.Options)
+	}
+	if q.Type == "multi_choice" {
+		input.CorrectAnswer = ""
+		input.CorrectAnswers = common.ParseJSONArray(q.CorrectAnswer)
+	}
+	if config, ok := parseBlankConfig(q); ok {
+		input.BlankConfig = &config
+	}
+	if q.TestCases != "" {
+		json.Unmarshal([]byte(q.TestCases), &input.TestCases)
+	}
+	return input
+}
@@ -0,0 +1,516 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"lh/common"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BankQuestionInput 题库题目输入结构体
+type BankQuestionInput struct {
+	Type              string                   `json:"type" binding:"required,oneof=choice multi_choice blank numeric true_false essay code"`
+	Content           string                   `json:"content" binding:"required"`
+	Options           []string                 `json:"options" binding:"required_if=Type choice required_if=Type multi_choice"`
+	CorrectAnswer     string                   `json:"correct_answer" binding:"required_if=Type choice required_if=Type numeric required_if=Type true_false"`
+	BlankConfig       *BlankConfig             `json:"blank_config"`
+	Rubric            []RubricCriterion        `json:"rubric"`
+	CorrectAnswers    []string                 `json:"correct_answers" binding:"required_if=Type multi_choice"`
+	ScoringMode       string                   `json:"scoring_mode" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
+	Tolerance         float64                  `json:"tolerance" binding:"gte=0"`
+	ToleranceType     string                   `json:"tolerance_type" binding:"omitempty,oneof=absolute relative"`
+	Unit              string                   `json:"unit" binding:"omitempty,max=20"`
+	Score             int                      `json:"score" binding:"required,gt=0"`
+	ImageURL          string                   `json:"image_url"`
+	Explanation       string                   `json:"explanation"`
+	TestCases         []TestCase               `json:"test_cases" binding:"required_if=Type code,dive"`
+	TimeLimit         int                      `json:"time_limit" binding:"gte=0,lte=60"`
+	MemoryLimitMB     int                      `json:"memory_limit_mb" binding:"gte=0,lte=4096"`
+	LanguageLimits    map[string]ResourceLimit `json:"language_limits" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	CompareMode       string                   `json:"compare_mode" binding:"omitempty,oneof=exact ignore_whitespace float checker"`
+	FloatEpsilon      float64                  `json:"float_epsilon" binding:"gte=0"`
+	Checker           *CheckerProgram          `json:"checker"`
+	StarterCode       map[string]string        `json:"starter_code" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	Harness           map[string]string        `json:"harness" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`
+	ReferenceSolution *ReferenceSolution       `json:"reference_solution"`
+	ValidateTestCases string                   `json:"validate_test_cases" binding:"omitempty,oneof=reject flag"` // 用参考答案校验测试用例
+	Difficulty        string                   `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	Tags              []string                 `json:"tags"`
+	Topics            []string                 `json:"topics"`
+}
+
+// UpdateBankQuestionInput 题库题目更新结构体，只更新提供的字段
+type UpdateBankQuestionInput struct {
+	Type              string                   `json:"type" binding:"omitempty,oneof=choice multi_choice blank numeric true_false essay code"`
+	Content           string                   `json:"content"`
+	Options           []string                 `json:"options"`
+	CorrectAnswer     string                   `json:"correct_answer"`
+	CorrectAnswers    []string                 `json:"correct_answers"`
+	BlankConfig       *BlankConfig             `json:"blank_config"`
+	Rubric            []RubricCriterion        `json:"rubric"`
+	ScoringMode       string                   `json:"scoring_mode" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
+	Tolerance         *float64                 `json:"tolerance" binding:"omitempty,gte=0"`
+	ToleranceType     string                   `json:"tolerance_type" binding:"omitempty,oneof=absolute relative"`
+	Unit              *string                  `json:"unit" binding:"omitempty,max=20"`
+	Score             int                      `json:"score" binding:"omitempty,gt=0"`
+	ImageURL          *string                  `json:"image_url"`
+	Explanation       *string                  `json:"explanation"`
+	TestCases         []TestCase               `json:"test_cases" binding:"omitempty,dive"`
+	TimeLimit         *int                     `json:"time_limit" binding:"omitempty,gte=0,lte=60"`
+	MemoryLimitMB     *int                     `json:"memory_limit_mb" binding:"omitempty,gte=0,lte=4096"`
+	LanguageLimits    map[string]ResourceLimit `json:"language_limits" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"` // 传空对象表示清除
+	CompareMode       string                   `json:"compare_mode" binding:"omitempty,oneof=exact ignore_whitespace float checker"`
+	FloatEpsilon      *float64                 `json:"float_epsilon" binding:"omitempty,gte=0"`
+	Checker           *CheckerProgram          `json:"checker"`
+	StarterCode       map[string]string        `json:"starter_code" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"` // 传空对象表示清除
+	Harness           map[string]string        `json:"harness" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`      // 传空对象表示清除
+	ReferenceSolution *ReferenceSolution       `json:"reference_solution"`
+	ValidateTestCases string                   `json:"validate_test_cases" binding:"omitempty,oneof=reject flag"` // 用参考答案校验测试用例
+	Difficulty        string                   `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	Tags              []string                 `json:"tags"`
+	Topics            []string                 `json:"topics"`
+}
+
+func toJSONString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func bankQuestionResponse(q models.BankQuestion) gin.H {
+	data := gin.H{
+		"bank_question_id": q.ID,
+		"type":             q.Type,
+		"content":          q.Content,
+		"correct_answer":   q.CorrectAnswer,
+		"score":            q.Score,
+		"image_url":        q.ImageURL,
+		"explanation":      q.Explanation,
+		"difficulty":       q.Difficulty,
+		"tags":             common.ParseJSONArray(q.Tags),
+		"topics":           common.ParseJSONArray(q.Topics),
+		"created_at":       q.CreatedAt.Format(time.RFC3339),
+		"updated_at":       q.UpdatedAt.Format(time.RFC3339),
+	}
+	if q.Type == "choice" {
+		data["options"] = common.ParseJSONArray(q.Options)
+	}
+	if q.Type == "multi_choice" {
+		data["options"] = common.ParseJSONArray(q.Options)
+		data["correct_answers"] = common.ParseJSONArray(q.CorrectAnswer)
+		data["scoring_mode"] = q.ScoringMode
+	}
+	if q.Type == "blank" && q.BlankConfig != "" {
+		var config BlankConfig
+		if err := json.Unmarshal([]byte(q.BlankConfig), &config); err == nil {
+			data["blank_config"] = config
+		}
+	}
+	if q.Rubric != "" {
+		var rubric []RubricCriterion
+		if err := json.Unmarshal([]byte(q.Rubric), &rubric); err == nil {
+			data["rubric"] = rubric
+		}
+	}
+	if q.Type == "numeric" {
+		data["tolerance"] = q.Tolerance
+		data["tolerance_type"] = q.ToleranceType
+		data["unit"] = q.Unit
+	}
+	if q.Type == "code" {
+		var testCases []TestCase
+		if err := json.Unmarshal([]byte(q.TestCases), &testCases); err == nil {
+			data["test_cases"] = testCases
+		}
+		question := bankAsQuestion(q)
+		data["time_limit"] = q.TimeLimit
+		data["memory_limit_mb"] = q.MemoryLimitMB
+		data["language_limits"] = parseLanguageLimits(question)
+		data["compare_mode"] = compareMode(question)
+		data["float_epsilon"] = q.FloatEpsilon
+		data["checker"] = parseChecker(question)
+		data["starter_code"] = parseLanguageCode(q.StarterCode)
+		data["harness"] = parseLanguageCode(q.Harness)
+		data["reference_solution"] = parseReferenceSolution(question)
+	}
+	return data
+}
+
+// testCaseWarnings 将单道题的参考答案警告转换为列表，与实验接口的 warnings 格式一致
+func testCaseWarnings(warning *TestCaseWarning) []TestCaseWarning {
+	if warning == nil {
+		return nil
+	}
+	return []TestCaseWarning{*warning}
+}
+
+// bankAsQuestion 将题库题目转换为实验题目，只包含题目内容，不设置 ID 和所属实验
+func bankAsQuestion(bq models.BankQuestion) models.Question {
+	return models.Question{
+		Type:              bq.Type,
+		Content:           bq.Content,
+		Options:           bq.Options,
+		CorrectAnswer:     bq.CorrectAnswer,
+		Score:             bq.Score,
+		ScoringMode:       bq.ScoringMode,
+		Tolerance:         bq.Tolerance,
+		ToleranceType:     bq.ToleranceType,
+		Unit:              bq.Unit,
+		BlankConfig:       bq.BlankConfig,
+		Rubric:            bq.Rubric,
+		ImageURL:          bq.ImageURL,
+		TestCases:         bq.TestCases,
+		Explanation:       bq.Explanation,
+		TimeLimit:         bq.TimeLimit,
+		MemoryLimitMB:     bq.MemoryLimitMB,
+		LanguageLimits:    bq.LanguageLimits,
+		CompareMode:       bq.CompareMode,
+		FloatEpsilon:      bq.FloatEpsilon,
+		Checker:           bq.Checker,
+		StarterCode:       bq.StarterCode,
+		Harness:           bq.Harness,
+		ReferenceSolution: bq.ReferenceSolution,
+		Difficulty:        bq.Difficulty,
+	}
+}
+
+// setBankContent 用校验后生成的题目内容覆盖题库题目
+func setBankContent(bq *models.BankQuestion, q models.Question) {
+	bq.Type = q.Type
+	bq.Content = q.Content
+	bq.Options = q.Options
+	bq.CorrectAnswer = q.CorrectAnswer
+	bq.Score = q.Score
+	bq.ScoringMode = q.ScoringMode
+	bq.Tolerance = q.Tolerance
+	bq.ToleranceType = q.ToleranceType
+	bq.Unit = q.Unit
+	bq.BlankConfig = q.BlankConfig
+	bq.Rubric = q.Rubric
+	bq.ImageURL = q.ImageURL
+	bq.TestCases = q.TestCases
+	bq.Explanation = q.Explanation
+	bq.TimeLimit = q.TimeLimit
+	bq.MemoryLimitMB = q.MemoryLimitMB
+	bq.LanguageLimits = q.LanguageLimits
+	bq.CompareMode = q.CompareMode
+	bq.FloatEpsilon = q.FloatEpsilon
+	bq.Checker = q.Checker
+	bq.StarterCode = q.StarterCode
+	bq.Harness = q.Harness
+	bq.ReferenceSolution = q.ReferenceSolution
+	bq.Difficulty = q.Difficulty
+}
+
+// 辅助函数：按 ID 顺序把教师题库中的题目复制为实验题目
+func copyBankQuestions(db *gorm.DB, ids []string, teacherID uint, experimentID string) ([]models.Question, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var bankQuestions []models.BankQuestion
+	if err := db.Where("id IN ? AND teacher_id = ?", ids, teacherID).Find(&bankQuestions).Error; err != nil {
+		return nil, err
+	}
+	bankMap := make(map[string]models.BankQuestion, len(bankQuestions))
+	for _, bq := range bankQuestions {
+		bankMap[bq.ID] = bq
+	}
+	now := time.Now()
+	questions := make([]models.Question, 0, len(ids))
+	for _, id := range ids {
+		bq, ok := bankMap[id]
+		if !ok {
+			return nil, errors.New("题库题目 " + id + " 不存在或不属于当前教师")
+		}
+		question := bankAsQuestion(bq)
+		question.ID = uuid.NewString()
+		question.ExperimentID = experimentID
+		question.BankQuestionID = bq.ID
+		question.CreatedAt = now
+		question.UpdatedAt = now
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+// CreateBankQuestion 向当前教师的题库中添加题目
+func CreateBankQuestion(c *gin.Context) {
+	var req BankQuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	id := uuid.NewString()
+	content, warning, err := buildQuestion(id, "", QuestionInput{
+		Type:              req.Type,
+		Content:           req.Content,
+		Options:           req.Options,
+		CorrectAnswer:     req.CorrectAnswer,
+		BlankConfig:       req.BlankConfig,
+		Rubric:            req.Rubric,
+		CorrectAnswers:    req.CorrectAnswers,
+		ScoringMode:       req.ScoringMode,
+		Tolerance:         req.Tolerance,
+		ToleranceType:     req.ToleranceType,
+		Unit:              req.Unit,
+		Score:             req.Score,
+		ImageURL:          req.ImageURL,
+		Explanation:       req.Explanation,
+		TestCases:         req.TestCases,
+		TimeLimit:         req.TimeLimit,
+		MemoryLimitMB:     req.MemoryLimitMB,
+		LanguageLimits:    req.LanguageLimits,
+		CompareMode:       req.CompareMode,
+		FloatEpsilon:      req.FloatEpsilon,
+		Checker:           req.Checker,
+		StarterCode:       req.StarterCode,
+		Harness:           req.Harness,
+		ReferenceSolution: req.ReferenceSolution,
+		ValidateTestCases: req.ValidateTestCases,
+		Difficulty:        req.Difficulty,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	question := models.BankQuestion{
+		ID:        id,
+		TeacherID: currentUser(c).ID,
+		Tags:      toJSONString(req.Tags),
+		Topics:    toJSONString(req.Topics),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	setBankContent(&question, content)
+	if err := global.DB.Create(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存题目失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   "success",
+		"data":     bankQuestionResponse(question),
+		"warnings": testCaseWarnings(warning),
+	})
+}
+
+// GetBankQuestions 搜索当前教师的题库，支持关键词、题型、难度、标签和知识点筛选
+func GetBankQuestions(c *gin.Context) {
+	db := common.GetDB()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.BankQuestion{}).Where("teacher_id = ?", currentUser(c).ID)
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("content LIKE ?", "%"+keyword+"%")
+	}
+	if questionType := c.Query("type"); questionType != "" {
+		query = query.Where("type = ?", questionType)
+	}
+	if difficulty := c.Query("difficulty"); difficulty != "" {
+		query = query.Where("difficulty = ?", difficulty)
+	}
+	// 标签和知识点以 JSON 数组存储，按带引号的完整元素匹配
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("tags LIKE ?", "%"+toJSONString(tag)+"%")
+	}
+	if topic := c.Query("topic"); topic != "" {
+		query = query.Where("topics LIKE ?", "%"+toJSONString(topic)+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var questions []models.BankQuestion
+	if err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		return
+	}
+	response := make([]gin.H, len(questions))
+	for i, q := range questions {
+		response[i] = bankQuestionResponse(q)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   response,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// 辅助函数：加载当前教师的题库题目，失败时直接写入响应
+func loadBankQuestion(c *gin.Context, db *gorm.DB) (models.BankQuestion, bool) {
+	var question models.BankQuestion
+	if err := db.First(&question, "id = ?", c.Param("question_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "题目不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "数据库查询失败"})
+		}
+		return question, false
+	}
+	if question.TeacherID != currentUser(c).ID {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "无权访问该题目"})
+		return question, false
+	}
+	return question, true
+}
+
+// GetBankQuestionDetail 获取题库题目详情
+func GetBankQuestionDetail(c *gin.Context) {
+	question, ok := loadBankQuestion(c, common.GetDB())
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   bankQuestionResponse(question),
+	})
+}
+
+// UpdateBankQuestion 更新题库题目，已复制到实验中的题目不受影响
+func UpdateBankQuestion(c *gin.Context) {
+	var req UpdateBankQuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	question, ok := loadBankQuestion(c, db)
+	if !ok {
+		return
+	}
+	// 在原有内容上覆盖提交的字段，再按新建题目的规则重新校验
+	input := questionInput(bankAsQuestion(question))
+	if req.Type != "" {
+		input.Type = req.Type
+	}
+	if req.Content != "" {
+		input.Content = req.Content
+	}
+	if req.Options != nil {
+		input.Options = req.Options
+	}
+	if req.CorrectAnswer != "" {
+		input.CorrectAnswer = req.CorrectAnswer
+	}
+	if req.CorrectAnswers != nil {
+		input.CorrectAnswers = req.CorrectAnswers
+	}
+	if req.Score > 0 {
+		input.Score = req.Score
+	}
+	if req.ImageURL != nil {
+		input.ImageURL = *req.ImageURL
+	}
+	if req.Explanation != nil {
+		input.Explanation = *req.Explanation
+	}
+	if req.Difficulty != "" {
+		input.Difficulty = req.Difficulty
+	}
+	if req.ScoringMode != "" {
+		input.ScoringMode = req.ScoringMode
+	}
+	if req.Tolerance != nil {
+		input.Tolerance = *req.Tolerance
+	}
+	if req.ToleranceType != "" {
+		input.ToleranceType = req.ToleranceType
+	}
+	if req.Unit != nil {
+		input.Unit = *req.Unit
+	}
+	// 修改填空配置但未给出参考答案时，参考答案按新配置重新生成
+	if req.BlankConfig != nil {
+		input.BlankConfig = req.BlankConfig
+		input.CorrectAnswer = req.CorrectAnswer
+	}
+	if req.Rubric != nil {
+		input.Rubric = req.Rubric
+	}
+	if req.TestCases != nil {
+		input.TestCases = req.TestCases
+	}
+	if req.TimeLimit != nil {
+		input.TimeLimit = *req.TimeLimit
+	}
+	if req.MemoryLimitMB != nil {
+		input.MemoryLimitMB = *req.MemoryLimitMB
+	}
+	if req.LanguageLimits != nil {
+		input.LanguageLimits = req.LanguageLimits
+	}
+	if req.FloatEpsilon != nil {
+		input.FloatEpsilon = *req.FloatEpsilon
+	}
+	if req.StarterCode != nil {
+		input.StarterCode = req.StarterCode
+	}
+	if req.Harness != nil {
+		input.Harness = req.Harness
+	}
+	if req.ReferenceSolution != nil {
+		input.ReferenceSolution = req.ReferenceSolution
+	}
+	// 只修改比较方式为 checker 时保留原有检查程序，改为其他方式时清除
+	if req.CompareMode != "" || req.Checker != nil {
+		if req.Checker != nil || req.CompareMode != compareChecker {
+			input.Checker = req.Checker
+		}
+		input.CompareMode = req.CompareMode
+	}
+	input.ValidateTestCases = req.ValidateTestCases
+	content, warning, err := buildQuestion(question.ID, "", input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	setBankContent(&question, content)
+	if req.Tags != nil {
+		question.Tags = toJSONString(req.Tags)
+	}
+	if req.Topics != nil {
+		question.Topics = toJSONString(req.Topics)
+	}
+	question.UpdatedAt = time.Now()
+	if err := db.Save(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "更新题目失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"data":     bankQuestionResponse(question),
+		"warnings": testCaseWarnings(warning),
+	})
+}
+
+// DeleteBankQuestion 删除题库题目，已复制到实验中的题目保留
+func DeleteBankQuestion(c *gin.Context) {
+	db := global.DB
+	question, ok := loadBankQuestion(c, db)
+	if !ok {
+		return
+	}
+	if err := db.Delete(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "删除题目失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "题目已删除",
+	})
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBankQuestion_SearchAndCopyIntoExperiment(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+
+	body := `{"type":"choice","content":"进程调度","options":["FCFS","SJF"],"correct_answer":"FCFS","score":5,"difficulty":"easy","tags":["操作系统","调度"],"topics":["进程"]}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Request = httptest.NewRequest("POST", "/question-bank", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	CreateBankQuestion(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data struct {
+			ID string `json:"bank_question_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	search := func(query string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Request = httptest.NewRequest("GET", "/question-bank?"+query, nil)
+		GetBankQuestions(c)
+		var resp struct {
+			Data []interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return len(resp.Data)
+	}
+	assert.Equal(t, 1, search("tag=调度&difficulty=easy"))
+	assert.Equal(t, 0, search("tag=调"))
+	assert.Equal(t, 0, search("difficulty=hard"))
+
+	deadline := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	body = `{"title":"题库组卷","permission":1,"deadline":"` + deadline + `","student_ids":["` + strconv.Itoa(int(stu.ID)) + `"],"bank_question_ids":["` + created.Data.ID + `"]}`
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Request = httptest.NewRequest("POST", "/experiments", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	CreateExperiment(c)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// 修改题库不影响已复制的实验题目
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "question_id", Value: created.Data.ID}}
+	c.Request = httptest.NewRequest("PUT", "/question-bank", bytes.NewBufferString(`{"correct_answer":"SJF"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	UpdateBankQuestion(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var copied models.Question
+	global.DB.Where("bank_question_id = ?", created.Data.ID).First(&copied)
+	assert.Equal(t, "FCFS", copied.CorrectAnswer)
+	assert.NotEqual(t, created.Data.ID, copied.ID)
+}
+
+func TestBankQuestion_OtherTeacherForbidden(t *testing.T) {
+	setupTestDBTeacher(t)
+	owner := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+	global.DB.Create(&models.BankQuestion{ID: "bq-1", TeacherID: owner.ID, Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 1})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", other)
+	c.Params = gin.Params{{Key: "question_id", Value: "bq-1"}}
+	c.Request = httptest.NewRequest("DELETE", "/question-bank/bq-1", nil)
+	DeleteBankQuestion(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	_, err := copyBankQuestions(global.DB, []string{"bq-1"}, other.ID, "exp")
+	assert.Error(t, err)
+}
+
+func TestBankQuestion_CodeSettingsCopied(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	fakeJudgeOutputs(t, "3", "5")
+
+	send := func(method, id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Request = httptest.NewRequest(method, "/question-bank", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if id == "" {
+			CreateBankQuestion(c)
+		} else {
+			c.Params = gin.Params{{Key: "question_id", Value: id}}
+			UpdateBankQuestion(c)
+		}
+		return w
+	}
+
+	// 代码题的设置与创建实验时做相同的校验
+	w := send("POST", "", `{"type":"code","content":"a+b","score":10,"test_cases":[{"input":"1 2","expected_output":"3"}],"compare_mode":"checker"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body := `{"type":"code","content":"a+b","score":10,
+		"test_cases":[{"input":"1 2","expected_output":"3"},{"input":"2 2","expected_output":"4"}],
+		"time_limit":3,"memory_limit_mb":128,"language_limits":{"java":{"time_limit":6}},
+		"checker":{"language":"python","source_code":"import sys"},
+		"starter_code":{"python":"def add(a, b):\n    pass"},"harness":{"python":"print(add(*map(int, input().split())))"},
+		"reference_solution":{"language":"python","source_code":"print(3)"},"validate_test_cases":"flag"}`
+	w = send("POST", "", body)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Data struct {
+			ID          string            `json:"bank_question_id"`
+			CompareMode string            `json:"compare_mode"`
+			StarterCode map[string]string `json:"starter_code"`
+		} `json:"data"`
+		Warnings []TestCaseWarning `json:"warnings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, compareChecker, created.Data.CompareMode)
+	assert.Equal(t, "def add(a, b):\n    pass", created.Data.StarterCode["python"])
+	if assert.Len(t, created.Warnings, 1) {
+		assert.Equal(t, created.Data.ID, created.Warnings[0].QuestionID)
+	}
+
+	questions, err := copyBankQuestions(global.DB, []string{created.Data.ID}, teacher.ID, "exp")
+	if assert.NoError(t, err) && assert.Len(t, questions, 1) {
+		q := questions[0]
+		assert.Equal(t, 3, q.TimeLimit)
+		assert.Equal(t, 128, q.MemoryLimitMB)
+		assert.Equal(t, 6, parseLanguageLimits(q)["java"].TimeLimit)
+		assert.Equal(t, compareChecker, q.CompareMode)
+		assert.Equal(t, "import sys", parseChecker(q).SourceCode)
+		assert.Equal(t, "print(add(*map(int, input().split())))", parseLanguageCode(q.Harness)["python"])
+		assert.Equal(t, "print(3)", parseReferenceSolution(q).SourceCode)
+	}
+
+	// 改为其他比较方式时清除检查程序
+	w = send("PUT", created.Data.ID, `{"compare_mode":"float","float_epsilon":0.01}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var stored models.BankQuestion
+	global.DB.First(&stored, "id = ?", created.Data.ID)
+	assert.Equal(t, compareFloat, stored.CompareMode)
+	assert.Equal(t, 0.01, stored.FloatEpsilon)
+	assert.Empty(t, stored.Checker)
+	assert.Equal(t, 3, stored.TimeLimit)
+}
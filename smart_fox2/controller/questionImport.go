@@ -0,0 +1,716 @@
+package controller
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"lh/global"
+	"lh/models"
+	"math"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 题目导入支持的文件格式
+const (
+	importGIFT      = "gift"
+	importMoodleXML = "moodle_xml"
+	importMarkdown  = "markdown"
+)
+
+// importedItem 从导入文件中解析出的一道题，无法转换时 Reason 为跳过原因
+type importedItem struct {
+	Title    string
+	Question *BundleQuestion
+	Reason   string
+}
+
+// ImportReportItem 导入报告中的一项
+type ImportReportItem struct {
+	Index      int    `json:"index"`
+	Title      string `json:"title"`
+	Type       string `json:"type,omitempty"`
+	Status     string `json:"status"` // imported 或 skipped
+	Reason     string `json:"reason,omitempty"`
+	QuestionID string `json:"question_id,omitempty"`
+}
+
+// importFormat 根据参数或文件扩展名判断导入格式
+func importFormat(format, filename string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gift", ".txt":
+		return importGIFT
+	case ".xml":
+		return importMoodleXML
+	case ".md", ".markdown":
+		return importMarkdown
+	}
+	return ""
+}
+
+// parseImportFile 按格式解析导入文件
+func parseImportFile(format string, data []byte) ([]importedItem, error) {
+	switch format {
+	case importGIFT:
+		return parseGIFT(string(data)), nil
+	case importMoodleXML:
+		return parseMoodleXML(data)
+	case importMarkdown:
+		return parseMarkdownQuestions(string(data)), nil
+	}
+	return nil, fmt.Errorf("不支持的导入格式 %s", format)
+}
+
+// importTitle 报告中显示的题目名称，没有标题时截取题干开头
+func importTitle(title, content string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	if len(runes) > 30 {
+		return string(runes[:30]) + "…"
+	}
+	return string(runes)
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText 将 Moodle 保存的 HTML 文本转换为纯文本
+func htmlToText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+	return strings.TrimSpace(blankRuns.ReplaceAllString(s, "\n\n"))
+}
+
+// ---------------- GIFT ----------------
+
+// GIFT 转义字符在解析时先替换为私有区字符，避免与语法符号混淆
+var giftEscapes = strings.NewReplacer(`\~`, "\uE000", `\=`, "\uE001", `\#`, "\uE002", `\{`, "\uE003", `\}`, "\uE004", `\:`, "\uE005", `\\`, "\uE006", `\n`, "\n")
+var giftUnescapes = strings.NewReplacer("\uE000", "~", "\uE001", "=", "\uE002", "#", "\uE003", "{", "\uE004", "}", "\uE005", ":", "\uE006", `\`)
+
+var (
+	giftWeight = regexp.MustCompile(`^%(-?[\d.]+)%`)
+	giftFormat = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+)
+
+// giftText 还原转义字符并去掉首尾空白
+func giftText(s string) string {
+	return strings.TrimSpace(giftUnescapes.Replace(s))
+}
+
+// giftAnswer GIFT 答案块中的一个答案
+type giftAnswer struct {
+	Correct bool    // 以 = 开头
+	Weight  float64 // %n% 指定的得分比例，未指定时正确答案为 100，错误答案为 0
+	Text    string
+}
+
+// parseGIFTAnswers 解析以 = 或 ~ 开头的答案列表，忽略各答案的反馈
+func parseGIFTAnswers(block string) []giftAnswer {
+	var answers []giftAnswer
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		answer := giftAnswer{Correct: block[start] == '='}
+		text := block[start+1 : end]
+		if m := giftWeight.FindStringSubmatch(text); m != nil {
+			answer.Weight, _ = strconv.ParseFloat(m[1], 64)
+			text = text[len(m[0]):]
+		} else if answer.Correct {
+			answer.Weight = 100
+		}
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		answer.Text = giftText(text)
+		answers = append(answers, answer)
+	}
+	for i := 0; i < len(block); i++ {
+		if block[i] == '=' || block[i] == '~' {
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(block))
+	return answers
+}
+
+// giftNumeric 解析数值答案，支持 值:误差 和 最小值..最大值 两种写法
+func giftNumeric(spec string) (string, float64, bool) {
+	if i := strings.Index(spec, "#"); i >= 0 {
+		spec = spec[:i]
+	}
+	spec = giftText(spec)
+	if low, high, ok := strings.Cut(spec, ".."); ok {
+		lo, err1 := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		hi, err2 := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if err1 != nil || err2 != nil {
+			return "", 0, false
+		}
+		return strconv.FormatFloat((lo+hi)/2, 'f', -1, 64), math.Abs(hi-lo) / 2, true
+	}
+	value, tolerance, _ := strings.Cut(spec, ":")
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+		return "", 0, false
+	}
+	t, _ := strconv.ParseFloat(strings.TrimSpace(tolerance), 64)
+	return strings.TrimSpace(value), t, true
+}
+
+// parseGIFTQuestion 解析一道 GIFT 题目
+func parseGIFTQuestion(text string) importedItem {
+	var item importedItem
+	if strings.HasPrefix(text, "::") {
+		if title, rest, ok := strings.Cut(text[2:], "::"); ok {
+			item.Title, text = giftText(title), rest
+		}
+	}
+	text = strings.TrimSpace(text)
+	isHTML := strings.HasPrefix(text, "[html]")
+	if m := giftFormat.FindString(text); m != "" {
+		text = text[len(m):]
+	}
+	open, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if open < 0 || end < open {
+		item.Title = importTitle(item.Title, giftText(text))
+		item.Reason = "缺少答案部分"
+		return item
+	}
+	before, block, after := text[:open], strings.TrimSpace(text[open+1:end]), strings.TrimSpace(text[end+1:])
+	item.Title = importTitle(item.Title, giftText(before))
+
+	bq := &BundleQuestion{}
+	if i := strings.Index(block, "####"); i >= 0 {
+		bq.Explanation = giftText(block[i+4:])
+		block = strings.TrimSpace(block[:i])
+	}
+	switch head := strings.ToUpper(strings.TrimSpace(strings.SplitN(block, "#", 2)[0])); {
+	case block == "":
+		bq.Type = "essay"
+	case head == "T" || head == "TRUE" || head == "F" || head == "FALSE":
+		bq.Type = "true_false"
+		bq.CorrectAnswer = strconv.FormatBool(strings.HasPrefix(head, "T"))
+	case strings.HasPrefix(block, "#"):
+		bq.Type = "numeric"
+		spec := strings.TrimSpace(block[1:])
+		if strings.HasPrefix(spec, "=") {
+			spec = ""
+			for _, answer := range parseGIFTNumericAnswers(block[1:]) {
+				if answer.Weight >= 100 {
+					spec = answer.Text
+					break
+				}
+			}
+		}
+		value, tolerance, ok := giftNumeric(spec)
+		if !ok {
+			item.Reason = "无法识别数值答案"
+			return item
+		}
+		bq.CorrectAnswer, bq.Tolerance = value, tolerance
+	case strings.Contains(block, "->"):
+		item.Reason = "不支持的题型 matching"
+		return item
+	default:
+		answers := parseGIFTAnswers(block)
+		wrong := false
+		var options, correct []string
+		for _, answer := range answers {
+			options = append(options, answer.Text)
+			if answer.Weight > 0 {
+				correct = append(correct, answer.Text)
+			}
+			wrong = wrong || !answer.Correct
+		}
+		switch {
+		case len(correct) == 0:
+			item.Reason = "没有正确答案"
+			return item
+		case !wrong:
+			// 只有 = 开头的答案为简答题，按填空题导入
+			bq.Type = "blank"
+			var accepted []string
+			for _, answer := range answers {
+				if answer.Weight >= 100 {
+					accepted = append(accepted, answer.Text)
+				}
+			}
+			bq.BlankConfig = &BlankConfig{Blanks: []BlankSpec{{Answers: accepted}}, IgnoreCase: true}
+		case len(correct) == 1:
+			bq.Type = "choice"
+			bq.Options, bq.CorrectAnswer = options, correct[0]
+		default:
+			bq.Type = "multi_choice"
+			bq.Options, bq.CorrectAnswers = options, correct
+			bq.ScoringMode = "proportional"
+		}
+	}
+
+	// 答案块后还有文字时为完形填空写法，答案块的位置作为空
+	content := before
+	if after != "" {
+		placeholder := "____"
+		if bq.Type == "blank" {
+			placeholder = "{{1}}"
+		}
+		content = strings.TrimRight(before, " ") + " " + placeholder + " " + after
+	}
+	bq.Content = giftText(content)
+	if isHTML {
+		bq.Content = htmlToText(bq.Content)
+	}
+	item.Question = bq
+	return item
+}
+
+// parseGIFTNumericAnswers 解析 {#=值:误差 =%50%值:误差} 形式的多个数值答案
+func parseGIFTNumericAnswers(block string) []giftAnswer {
+	var answers []giftAnswer
+	for _, part := range strings.Split(block, "=")[1:] {
+		answer := giftAnswer{Correct: true, Weight: 100}
+		if m := giftWeight.FindStringSubmatch(part); m != nil {
+			answer.Weight, _ = strconv.ParseFloat(m[1], 64)
+			part = part[len(m[0]):]
+		}
+		answer.Text = part
+		answers = append(answers, answer)
+	}
+	return answers
+}
+
+// parseGIFT 解析 GIFT 格式，题目之间以空行分隔，// 开头的行为注释，$CATEGORY 行忽略
+func parseGIFT(data string) []importedItem {
+	data = giftEscapes.Replace(strings.ReplaceAll(data, "\r\n", "\n"))
+	var items []importedItem
+	var lines []string
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		lines = nil
+		if text == "" || strings.HasPrefix(text, "$CATEGORY:") {
+			return
+		}
+		items = append(items, parseGIFTQuestion(text))
+	}
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return items
+}
+
+// ---------------- Moodle XML ----------------
+
+type moodleText struct {
+	Format string `xml:"format,attr"`
+	Text   string `xml:"text"`
+}
+
+// plain 返回纯文本，HTML 格式的内容去掉标签
+func (t moodleText) plain() string {
+	if t.Format == "" || t.Format == "html" {
+		return htmlToText(t.Text)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+type moodleAnswer struct {
+	Fraction  float64 `xml:"fraction,attr"`
+	Format    string  `xml:"format,attr"`
+	Text      string  `xml:"text"`
+	Tolerance string  `xml:"tolerance"`
+}
+
+func (a moodleAnswer) plain() string {
+	return moodleText{Format: a.Format, Text: a.Text}.plain()
+}
+
+type moodleQuestion struct {
+	Type            string         `xml:"type,attr"`
+	Name            moodleText     `xml:"name"`
+	QuestionText    moodleText     `xml:"questiontext"`
+	GeneralFeedback moodleText     `xml:"generalfeedback"`
+	DefaultGrade    string         `xml:"defaultgrade"`
+	Single          string         `xml:"single"`
+	UseCase         string         `xml:"usecase"`
+	Answers         []moodleAnswer `xml:"answer"`
+}
+
+// parseMoodleQuestion 将一道 Moodle 题目转换为对应题型
+func parseMoodleQuestion(mq moodleQuestion) importedItem {
+	bq := &BundleQuestion{
+		Content:     mq.QuestionText.plain(),
+		Explanation: mq.GeneralFeedback.plain(),
+	}
+	item := importedItem{Title: importTitle(strings.TrimSpace(mq.Name.Text), bq.Content)}
+	if grade, err := strconv.ParseFloat(strings.TrimSpace(mq.DefaultGrade), 64); err == nil {
+		bq.Score = int(math.Round(grade))
+	}
+
+	var options, correct, accepted []string
+	var best *moodleAnswer
+	for i, answer := range mq.Answers {
+		options = append(options, answer.plain())
+		if answer.Fraction > 0 {
+			correct = append(correct, answer.plain())
+		}
+		if answer.Fraction >= 100 {
+			accepted = append(accepted, answer.plain())
+			if best == nil {
+				best = &mq.Answers[i]
+			}
+		}
+	}
+	switch mq.Type {
+	case "multichoice":
+		switch {
+		case len(correct) == 0:
+			item.Reason = "没有正确答案"
+			return item
+		case mq.Single == "false" || mq.Single == "0":
+			bq.Type = "multi_choice"
+			bq.Options, bq.CorrectAnswers = options, correct
+			bq.ScoringMode = "proportional"
+		default:
+			bq.Type = "choice"
+			bq.Options, bq.CorrectAnswer = options, correct[0]
+			if best != nil {
+				bq.CorrectAnswer = best.plain()
+			}
+		}
+	case "truefalse":
+		if best == nil {
+			item.Reason = "没有正确答案"
+			return item
+		}
+		bq.Type = "true_false"
+		bq.CorrectAnswer = best.plain()
+	case "shortanswer":
+		if len(accepted) == 0 {
+			item.Reason = "没有正确答案"
+			return item
+		}
+		bq.Type = "blank"
+		bq.BlankConfig = &BlankConfig{Blanks: []BlankSpec{{Answers: accepted}}, IgnoreCase: mq.UseCase != "1"}
+	case "numerical":
+		if best == nil {
+			item.Reason = "没有正确答案"
+			return item
+		}
+		bq.Type = "numeric"
+		bq.CorrectAnswer = best.plain()
+		bq.Tolerance, _ = strconv.ParseFloat(strings.TrimSpace(best.Tolerance), 64)
+	case "essay":
+		bq.Type = "essay"
+	default:
+		item.Reason = "不支持的题型 " + mq.Type
+		return item
+	}
+	item.Question = bq
+	return item
+}
+
+// parseMoodleXML 解析 Moodle XML 格式，category 和 description 不是题目，直接忽略
+func parseMoodleXML(data []byte) ([]importedItem, error) {
+	var quiz struct {
+		Questions []moodleQuestion `xml:"question"`
+	}
+	if err := xml.Unmarshal(data, &quiz); err != nil {
+		return nil, fmt.Errorf("无法解析 Moodle XML: %v", err)
+	}
+	var items []importedItem
+	for _, mq := range quiz.Questions {
+		if mq.Type == "category" || mq.Type == "description" {
+			continue
+		}
+		items = append(items, parseMoodleQuestion(mq))
+	}
+	return items, nil
+}
+
+// ---------------- Markdown ----------------
+
+var (
+	markdownOption = regexp.MustCompile(`^[-*]\s+\[([ xX])\]\s+(.*)$`)
+	markdownField  = regexp.MustCompile(`^(?i)(type|answer|score|tolerance|unit|explanation|题型|答案|分值|误差|单位|解析)\s*[:：]\s*(.*)$`)
+)
+
+// markdownFieldNames 字段的中文写法
+var markdownFieldNames = map[string]string{
+	"题型": "type", "答案": "answer", "分值": "score", "误差": "tolerance", "单位": "unit", "解析": "explanation",
+}
+
+// parseMarkdownQuestion 解析一道 Markdown 题目
+func parseMarkdownQuestion(title string, lines []string) importedItem {
+	bq := &BundleQuestion{}
+	var body []string
+	var options, correct, answers []string
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if inFence || strings.HasPrefix(trimmed, "```") {
+			body = append(body, line)
+			continue
+		}
+		if m := markdownOption.FindStringSubmatch(trimmed); m != nil {
+			options = append(options, strings.TrimSpace(m[2]))
+			if m[1] != " " {
+				correct = append(correct, strings.TrimSpace(m[2]))
+			}
+			continue
+		}
+		m := markdownField.FindStringSubmatch(trimmed)
+		if m == nil {
+			body = append(body, line)
+			continue
+		}
+		key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+		if name, ok := markdownFieldNames[key]; ok {
+			key = name
+		}
+		switch key {
+		case "type":
+			bq.Type = strings.ToLower(value)
+		case "answer":
+			answers = append(answers, value)
+		case "score":
+			bq.Score, _ = strconv.Atoi(value)
+		case "tolerance":
+			bq.Tolerance, _ = strconv.ParseFloat(value, 64)
+		case "unit":
+			bq.Unit = value
+		case "explanation":
+			bq.Explanation = value
+		}
+	}
+	bq.Content = strings.TrimSpace(strings.Join(body, "\n"))
+	if bq.Content == "" {
+		bq.Content = title
+	}
+	item := importedItem{Title: importTitle(title, bq.Content)}
+
+	// 未指定题型时按内容推断
+	if bq.Type == "" {
+		switch {
+		case len(options) > 0 && len(correct) > 1:
+			bq.Type = "multi_choice"
+		case len(options) > 0:
+			bq.Type = "choice"
+		case len(answers) == 0:
+			bq.Type = "essay"
+		case blankPlaceholder.MatchString(bq.Content) || len(answers) > 1:
+			bq.Type = "blank"
+		default:
+			if _, err := parseNumericAnswer(answers[0], bq.Unit); err == nil {
+				bq.Type = "numeric"
+			} else if _, ok := parseTrueFalse(answers[0]); ok {
+				bq.Type = "true_false"
+			} else {
+				bq.Type = "blank"
+			}
+		}
+	}
+	switch bq.Type {
+	case "choice":
+		if len(correct) != 1 {
+			item.Reason = "单选题需要恰好一个正确选项"
+			return item
+		}
+		bq.Options, bq.CorrectAnswer = options, correct[0]
+	case "multi_choice":
+		bq.Options, bq.CorrectAnswers = options, correct
+	case "blank":
+		// 每行答案对应一个空，同一个空的多个可接受答案用 | 分隔
+		config := &BlankConfig{}
+		for _, answer := range answers {
+			var spec BlankSpec
+			for _, alternative := range strings.Split(answer, "|") {
+				if alternative = strings.TrimSpace(alternative); alternative != "" {
+					spec.Answers = append(spec.Answers, alternative)
+				}
+			}
+			config.Blanks = append(config.Blanks, spec)
+		}
+		bq.BlankConfig = config
+	case "numeric", "true_false":
+		if len(answers) == 0 {
+			item.Reason = "缺少答案"
+			return item
+		}
+		bq.CorrectAnswer = answers[0]
+	case "essay":
+	default:
+		item.Reason = "不支持的题型 " + bq.Type
+		return item
+	}
+	item.Question = bq
+	return item
+}
+
+// parseMarkdownQuestions 解析 Markdown 格式：每道题以二级标题 "## " 开始，标题之后为题干；
+// "- [x]" 和 "- [ ]" 列出选项，"答案:"、"分值:"、"误差:"、"单位:"、"解析:"、"题型:" 等字段行
+// 给出答案和设置（也可以写作 Answer、Score 等英文）。未写题型时按选项和答案推断
+func parseMarkdownQuestions(data string) []importedItem {
+	var items []importedItem
+	title, started := "", false
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "## ") {
+			if started {
+				items = append(items, parseMarkdownQuestion(title, lines))
+			}
+			title, started, lines = strings.TrimSpace(line[3:]), true, nil
+			continue
+		}
+		if started {
+			lines = append(lines, line)
+		}
+	}
+	if started {
+		items = append(items, parseMarkdownQuestion(title, lines))
+	}
+	return items
+}
+
+// ImportQuestions 从 GIFT、Moodle XML 或 Markdown 文件导入题目到实验，逐题返回导入或跳过的原因。
+// 表单字段 file 为导入文件，format 可指定格式，未指定时按扩展名判断；score 为文件未给出分值时的默认分值；
+// dry_run=true 时只生成报告不保存
+func ImportQuestions(c *gin.Context) {
+	db := global.DB
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请上传导入文件"})
+		return
+	}
+	format := importFormat(c.PostForm("format"), file.Filename)
+	if format != importGIFT && format != importMoodleXML && format != importMarkdown {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "导入格式只能是 gift、moodle_xml 或 markdown"})
+		return
+	}
+	defaultScore := 1
+	if value := c.PostForm("score"); value != "" {
+		if defaultScore, err = strconv.Atoi(value); err != nil || defaultScore <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "默认分值必须是正整数"})
+			return
+		}
+	}
+	dryRun := c.PostForm("dry_run") == "true"
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "无法读取导入文件"})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxBundleFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "无法读取导入文件"})
+		return
+	}
+	if len(data) > maxBundleFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "导入文件过大"})
+		return
+	}
+	items, err := parseImportFile(format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "文件中没有找到题目"})
+		return
+	}
+
+	// 导入的题目排在实验已有题目之后
+	now := time.Now()
+	var last models.Question
+	if err := db.Where("experiment_id = ?", experiment.ID).Order("created_at DESC").First(&last).Error; err == nil && !last.CreatedAt.Before(now) {
+		now = last.CreatedAt.Add(time.Millisecond)
+	}
+	report := make([]ImportReportItem, len(items))
+	var questions []models.Question
+	for i, item := range items {
+		report[i] = ImportReportItem{Index: i + 1, Title: item.Title, Status: "skipped", Reason: item.Reason}
+		if item.Question == nil {
+			continue
+		}
+		bq := *item.Question
+		report[i].Type = bq.Type
+		if bq.Score <= 0 {
+			bq.Score = defaultScore
+		}
+		if err := binding.Validator.ValidateStruct(&bq); err != nil {
+			report[i].Reason = err.Error()
+			continue
+		}
+		question, _, err := bq.toQuestion(experiment.ID)
+		if err != nil {
+			report[i].Reason = err.Error()
+			continue
+		}
+		question.CreatedAt = now.Add(time.Duration(len(questions)) * time.Millisecond)
+		question.UpdatedAt = now
+		questions = append(questions, question)
+		report[i].Status, report[i].QuestionID = "imported", question.ID
+	}
+
+	if !dryRun && len(questions) > 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&questions).Error
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存题目失败"})
+			return
+		}
+	}
+	if dryRun {
+		for i := range report {
+			report[i].QuestionID = ""
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("导入 %d 道题，跳过 %d 道", len(questions), len(items)-len(questions)),
+		"data": gin.H{
+			"format":   format,
+			"dry_run":  dryRun,
+			"imported": len(questions),
+			"skipped":  len(items) - len(questions),
+			"items":    report,
+		},
+	})
+}
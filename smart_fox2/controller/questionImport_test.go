@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGIFT(t *testing.T) {
+	data := `// 第一章
+$CATEGORY: $course$/第一章
+
+::首都::中国的首都是{=北京 ~上海 ~广州#不对}
+
+::偶数::下列哪些是偶数？{~%50%2 ~%50%4 ~%-100%3 ####2 和 4 能被 2 整除}
+
+::圆周率::圆周率约为 {#3.14:0.01}
+
+::范围::1 到 5 的中点 {#1..5}
+
+地球是圆的。{T}
+
+::简答::Go 语言的作者之一 {=Rob Pike =Pike}
+
+Go 使用 {=goroutine} 实现并发。
+
+::论述::谈谈你对并发的理解 {}
+
+::配对::{=a -> 1 =b -> 2}
+
+::转义::1 \= 1 \{吗\}？{TRUE}`
+	items := parseGIFT(data)
+	if !assert.Len(t, items, 10) {
+		return
+	}
+
+	q := items[0].Question
+	assert.Equal(t, "首都", items[0].Title)
+	assert.Equal(t, "choice", q.Type)
+	assert.Equal(t, "中国的首都是", q.Content)
+	assert.Equal(t, []string{"北京", "上海", "广州"}, q.Options)
+	assert.Equal(t, "北京", q.CorrectAnswer)
+
+	q = items[1].Question
+	assert.Equal(t, "multi_choice", q.Type)
+	assert.Equal(t, []string{"2", "4"}, q.CorrectAnswers)
+	assert.Equal(t, "2 和 4 能被 2 整除", q.Explanation)
+
+	q = items[2].Question
+	assert.Equal(t, "numeric", q.Type)
+	assert.Equal(t, "3.14", q.CorrectAnswer)
+	assert.Equal(t, 0.01, q.Tolerance)
+
+	q = items[3].Question
+	assert.Equal(t, "3", q.CorrectAnswer)
+	assert.Equal(t, 2.0, q.Tolerance)
+
+	assert.Equal(t, "true_false", items[4].Question.Type)
+	assert.Equal(t, "true", items[4].Question.CorrectAnswer)
+	assert.Equal(t, "地球是圆的。", items[4].Title)
+
+	q = items[5].Question
+	assert.Equal(t, "blank", q.Type)
+	assert.Equal(t, []string{"Rob Pike", "Pike"}, q.BlankConfig.Blanks[0].Answers)
+
+	// 答案块在句中时为完形填空
+	assert.Equal(t, "Go 使用 {{1}} 实现并发。", items[6].Question.Content)
+
+	assert.Equal(t, "essay", items[7].Question.Type)
+
+	assert.Nil(t, items[8].Question)
+	assert.Equal(t, "不支持的题型 matching", items[8].Reason)
+
+	assert.Equal(t, "1 = 1 {吗}？", items[9].Question.Content)
+}
+
+func TestParseMoodleXML(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="category"><category><text>$course$/默认</text></category></question>
+  <question type="multichoice">
+    <name><text>颜色</text></name>
+    <questiontext format="html"><text><![CDATA[<p>天空是什么颜色？</p>]]></text></questiontext>
+    <generalfeedback format="html"><text>晴天时是蓝色</text></generalfeedback>
+    <defaultgrade>2.0000000</defaultgrade>
+    <single>true</single>
+    <answer fraction="100" format="html"><text>蓝色</text></answer>
+    <answer fraction="0" format="html"><text>红色</text></answer>
+  </question>
+  <question type="multichoice">
+    <name><text>质数</text></name>
+    <questiontext format="html"><text>哪些是质数？</text></questiontext>
+    <single>false</single>
+    <answer fraction="50"><text>2</text></answer>
+    <answer fraction="50"><text>3</text></answer>
+    <answer fraction="-100"><text>4</text></answer>
+  </question>
+  <question type="shortanswer">
+    <name><text>单位</text></name>
+    <questiontext format="html"><text>力的单位</text></questiontext>
+    <usecase>0</usecase>
+    <answer fraction="100"><text>牛顿</text></answer>
+    <answer fraction="100"><text>N</text></answer>
+  </question>
+  <question type="numerical">
+    <name><text>重力加速度</text></name>
+    <questiontext format="html"><text>g 约为多少</text></questiontext>
+    <answer fraction="100"><text>9.8</text><tolerance>0.1</tolerance></answer>
+  </question>
+  <question type="truefalse">
+    <name><text>判断</text></name>
+    <questiontext format="html"><text>水在 100&amp;deg;C 沸腾</text></questiontext>
+    <answer fraction="100"><text>true</text></answer>
+    <answer fraction="0"><text>false</text></answer>
+  </question>
+  <question type="essay">
+    <name><text>论述</text></name>
+    <questiontext format="html"><text>谈谈牛顿定律</text></questiontext>
+  </question>
+  <question type="matching">
+    <name><text>配对</text></name>
+    <questiontext format="html"><text>连线</text></questiontext>
+  </question>
+</quiz>`
+	items, err := parseMoodleXML([]byte(data))
+	if !assert.NoError(t, err) || !assert.Len(t, items, 7) {
+		return
+	}
+
+	q := items[0].Question
+	assert.Equal(t, "颜色", items[0].Title)
+	assert.Equal(t, "choice", q.Type)
+	assert.Equal(t, "天空是什么颜色？", q.Content)
+	assert.Equal(t, "蓝色", q.CorrectAnswer)
+	assert.Equal(t, 2, q.Score)
+	assert.Equal(t, "晴天时是蓝色", q.Explanation)
+
+	assert.Equal(t, "multi_choice", items[1].Question.Type)
+	assert.Equal(t, []string{"2", "3"}, items[1].Question.CorrectAnswers)
+
+	q = items[2].Question
+	assert.Equal(t, "blank", q.Type)
+	assert.True(t, q.BlankConfig.IgnoreCase)
+	assert.Equal(t, []string{"牛顿", "N"}, q.BlankConfig.Blanks[0].Answers)
+
+	assert.Equal(t, "numeric", items[3].Question.Type)
+	assert.Equal(t, 0.1, items[3].Question.Tolerance)
+
+	assert.Equal(t, "true_false", items[4].Question.Type)
+	assert.Equal(t, "水在 100°C 沸腾", items[4].Question.Content)
+	assert.Equal(t, "essay", items[5].Question.Type)
+	assert.Equal(t, "不支持的题型 matching", items[6].Reason)
+
+	_, err = parseMoodleXML([]byte("<quiz><question>"))
+	assert.Error(t, err)
+}
+
+func TestParseMarkdownQuestions(t *testing.T) {
+	data := "# 第一章练习\n\n" +
+		"## 首都\n中国的首都是？\n- [x] 北京\n- [ ] 上海\n解析：北京是首都\n\n" +
+		"## 偶数\n- [x] 2\n- [ ] 3\n- [x] 4\n分值: 4\n\n" +
+		"## 圆周率\n圆周率保留两位小数是多少？\n答案：3.14\n误差：0.01\n\n" +
+		"## 判断\n地球是圆的\nAnswer: 对\n\n" +
+		"## 填空\n{{1}} 年，{{2}} 成立\n答案：1949\n答案：中华人民共和国 | 新中国\n\n" +
+		"## 代码\n下面的代码输出什么？\n```\n答案: 这不是字段\n```\n题型: essay\n\n" +
+		"## 无效\n- [ ] A\n- [ ] B\n"
+	items := parseMarkdownQuestions(data)
+	if !assert.Len(t, items, 7) {
+		return
+	}
+
+	q := items[0].Question
+	assert.Equal(t, "首都", items[0].Title)
+	assert.Equal(t, "choice", q.Type)
+	assert.Equal(t, "中国的首都是？", q.Content)
+	assert.Equal(t, "北京", q.CorrectAnswer)
+	assert.Equal(t, "北京是首都", q.Explanation)
+
+	q = items[1].Question
+	assert.Equal(t, "multi_choice", q.Type)
+	assert.Equal(t, "偶数", q.Content, "没有题干时使用标题")
+	assert.Equal(t, 4, q.Score)
+
+	assert.Equal(t, "numeric", items[2].Question.Type)
+	assert.Equal(t, 0.01, items[2].Question.Tolerance)
+	assert.Equal(t, "true_false", items[3].Question.Type)
+
+	q = items[4].Question
+	assert.Equal(t, "blank", q.Type)
+	if assert.Len(t, q.BlankConfig.Blanks, 2) {
+		assert.Equal(t, []string{"中华人民共和国", "新中国"}, q.BlankConfig.Blanks[1].Answers)
+	}
+
+	// 代码块中的内容不作为字段解析
+	assert.Equal(t, "essay", items[5].Question.Type)
+	assert.Contains(t, items[5].Question.Content, "答案: 这不是字段")
+
+	assert.Nil(t, items[6].Question)
+	assert.NotEmpty(t, items[6].Reason)
+}
+
+func TestImportQuestions(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	exp := models.Experiment{
+		ID: "exp-import", Title: "导入", Permission: 1, Deadline: time.Now().Add(time.Hour), TeacherID: teacher.ID,
+		Questions: []models.Question{{ID: "q-existing", Type: "essay", Content: "已有题目", Score: 5, CreatedAt: time.Now()}},
+	}
+	global.DB.Create(&exp)
+
+	upload := func(filename, content string, fields map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write([]byte(content))
+		for key, value := range fields {
+			writer.WriteField(key, value)
+		}
+		writer.Close()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+		c.Request = httptest.NewRequest("POST", "/questions/import", &body)
+		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		ImportQuestions(c)
+		return w
+	}
+	gift := "::首都::中国的首都是{=北京 ~上海}\n\n::配对::{=a -> 1 =b -> 2}\n\n::数值::1+1={#2}\n"
+	type report struct {
+		Data struct {
+			Imported int                `json:"imported"`
+			Skipped  int                `json:"skipped"`
+			Items    []ImportReportItem `json:"items"`
+		} `json:"data"`
+	}
+
+	// 预览时不保存
+	w := upload("bank.gift", gift, map[string]string{"dry_run": "true"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var preview report
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	assert.Equal(t, 2, preview.Data.Imported)
+	var count int64
+	global.DB.Model(&models.Question{}).Where("experiment_id = ?", exp.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w = upload("bank.txt", gift, map[string]string{"score": "3"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp report
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 2, resp.Data.Imported)
+	assert.Equal(t, 1, resp.Data.Skipped)
+	if assert.Len(t, resp.Data.Items, 3) {
+		assert.Equal(t, "imported", resp.Data.Items[0].Status)
+		assert.NotEmpty(t, resp.Data.Items[0].QuestionID)
+		assert.Equal(t, "skipped", resp.Data.Items[1].Status)
+		assert.Equal(t, "numeric", resp.Data.Items[2].Type)
+	}
+
+	var questions []models.Question
+	global.DB.Where("experiment_id = ?", exp.ID).Order("created_at ASC").Find(&questions)
+	if assert.Len(t, questions, 3) {
+		assert.Equal(t, "q-existing", questions[0].ID, "导入的题目排在已有题目之后")
+		assert.Equal(t, "北京", questions[1].CorrectAnswer)
+		assert.Equal(t, 3, questions[1].Score)
+		assert.Equal(t, "2", questions[2].CorrectAnswer)
+	}
+
+	w = upload("bank.pdf", gift, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 超过大小限制的文件直接拒绝，而不是截断后导入
+	w = upload("big.gift", gift+strings.Repeat(" ", maxBundleFileSize), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "导入文件过大")
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"lh/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildQuestion_RejectsBeforeJudging(t *testing.T) {
+	received := fakeJudgeOutputs(t, "3")
+	input := QuestionInput{Type: "code", Content: "a+b", Score: 5,
+		TestCases:         []TestCase{{Input: "1 2", ExpectedOutput: "3"}},
+		ReferenceSolution: &ReferenceSolution{Language: "python", SourceCode: "print(3)"},
+		ValidateTestCases: validateReject,
+		Rubric:            []RubricCriterion{{Name: "风格", Levels: []RubricLevel{{Label: "好", Points: 10}}}},
+	}
+	_, _, err := buildQuestion("q1", "exp1", input)
+	assert.Error(t, err)
+	// 评分细则不合法时不调用评测服务
+	assert.Empty(t, received.TestCases)
+
+	input.Rubric = nil
+	question, warning, err := buildQuestion("q1", "exp1", input)
+	assert.NoError(t, err)
+	assert.Nil(t, warning)
+	assert.Len(t, received.TestCases, 1)
+	assert.Equal(t, "q1", question.ID)
+	assert.Equal(t, "exp1", question.ExperimentID)
+}
+
+func TestBuildQuestion_RoundTrip(t *testing.T) {
+	// 已有题目还原为输入后重新生成，内容保持不变，更新实验时未修改的题目不会被改写
+	questions := []models.Question{
+		{Type: "choice", Content: "1+1=?", Score: 2, Options: `["1","2"]`, CorrectAnswer: "B"},
+		{Type: "multi_choice", Content: "偶数", Score: 3, Options: `["1","2","4"]`, CorrectAnswer: `["2","4"]`, ScoringMode: "proportional"},
+		{Type: "blank", Content: "{{1}} 和 {{2}}", Score: 4, CorrectAnswer: "a; b",
+			BlankConfig: `{"blanks":[{"answers":["a"],"score":2},{"answers":["b"],"score":2}],"ignore_case":false,"collapse_space":false,"full_width":false}`},
+		{Type: "numeric", Content: "g", Score: 1, CorrectAnswer: "9.8", Tolerance: 0.1, ToleranceType: "absolute", Unit: "m/s2"},
+		{Type: "true_false", Content: "对吗", Score: 1, CorrectAnswer: "true"},
+		{Type: "code", Content: "a+b", Score: 10, TestCases: `[{"input":"1 2","expected_output":"3"}]`,
+			TimeLimit: 3, CompareMode: compareChecker, Checker: `{"language":"python","source_code":"import sys"}`,
+			StarterCode: `{"python":"pass"}`, Rubric: `[{"name":"风格","levels":[{"label":"好","points":2}]}]`},
+		{Type: "essay", Content: "总结", Score: 5},
+	}
+	for _, q := range questions {
+		q.ID, q.ExperimentID = "q1", "exp1"
+		built, _, err := buildQuestion(q.ID, q.ExperimentID, questionInput(q))
+		if assert.NoError(t, err, q.Type) {
+			assert.Equal(t, q, built, q.Type)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 出题时用参考答案校验测试用例的方式
+const (
+	validateReject = "reject" // 参考答案未通过任一用例时拒绝保存
+	validateFlag   = "flag"   // 照常保存，在响应中标记未通过的用例
+)
+
+// ReferenceSolution 代码题的参考答案，用于校验和生成测试用例的期望输出，不向学生展示
+type ReferenceSolution struct {
+	Language   string `json:"language" binding:"required,oneof=cpp java python"`
+	SourceCode string `json:"source_code" binding:"required"`
+}
+
+// ReferenceFailure 参考答案未通过的测试用例
+type ReferenceFailure struct {
+	Index   int    `json:"index"`
+	Status  string `json:"status"`
+	Stdout  string `json:"stdout,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// TestCaseWarning 按 flag 方式校验时，某道题参考答案未通过的用例
+type TestCaseWarning struct {
+	QuestionID string             `json:"question_id"`
+	Failures   []ReferenceFailure `json:"failures"`
+}
+
+// parseReferenceSolution 读取题目的参考答案，未设置时返回 nil
+func parseReferenceSolution(question models.Question) *ReferenceSolution {
+	var reference ReferenceSolution
+	if question.ReferenceSolution == "" || json.Unmarshal([]byte(question.ReferenceSolution), &reference) != nil {
+		return nil
+	}
+	return &reference
+}
+
+// runReferenceSolution 用参考答案评测题目的全部测试用例，函数题同样与测试框架拼接
+func runReferenceSolution(question models.Question) (*EvaluationResponse, error) {
+	reference := parseReferenceSolution(question)
+	if reference == nil {
+		return nil, errors.New("题目没有设置参考答案")
+	}
+	source, err := assembleSource(question, reference.SourceCode, reference.Language)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateCode(source, reference.Language, question)
+}
+
+// referenceFailures 用参考答案校验测试用例，返回未通过的用例
+func referenceFailures(question models.Question) ([]ReferenceFailure, error) {
+	response, err := runReferenceSolution(question)
+	if err != nil {
+		return nil, err
+	}
+	var testCases []TestCase
+	json.Unmarshal([]byte(question.TestCases), &testCases)
+	failures := make([]ReferenceFailure, 0)
+	for i := range testCases {
+		failure := ReferenceFailure{Index: i + 1, Status: "Missing"}
+		if i < len(response.CaseResults) {
+			result := response.CaseResults[i]
+			failure.Status, failure.Stdout, failure.Message = result.Status, result.Stdout, result.CheckerMessage
+		}
+		if failure.Status != statusAccepted {
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+// checkReferenceSolution 按校验方式处理参考答案的评测结果：reject 时返回错误，flag 时返回警告
+func checkReferenceSolution(question models.Question, mode string) (*TestCaseWarning, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	failures, err := referenceFailures(question)
+	if err != nil {
+		return nil, fmt.Errorf("参考答案评测失败: %w", err)
+	}
+	if len(failures) == 0 {
+		return nil, nil
+	}
+	if mode == validateReject {
+		indexes := make([]string, len(failures))
+		for i, failure := range failures {
+			indexes[i] = strconv.Itoa(failure.Index)
+		}
+		return nil, fmt.Errorf("参考答案未通过测试用例 %s", strings.Join(indexes, "、"))
+	}
+	return &TestCaseWarning{QuestionID: question.ID, Failures: failures}, nil
+}
+
+// RegenerateExpectedOutputs 运行参考答案，用其输出重新生成代码题全部测试用例的期望输出
+func RegenerateExpectedOutputs(c *gin.Context) {
+	db := global.DB
+	experiment, ok := loadManagedExperiment(c, db)
+	if !ok {
+		return
+	}
+	var question models.Question
+	if err := db.First(&question, "id = ? AND experiment_id = ?", c.Param("question_id"), experiment.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "题目不存在"})
+		return
+	}
+	if question.Type != "code" || parseReferenceSolution(question) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "只有设置了参考答案的代码题可以生成期望输出"})
+		return
+	}
+
+	// 只需要参考答案的输出，按逐行比较评测，避免检查程序出错影响结果
+	plain := question
+	plain.CompareMode, plain.Checker = "", ""
+	response, err := runReferenceSolution(plain)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "参考答案评测失败: " + err.Error()})
+		return
+	}
+	var testCases []TestCase
+	json.Unmarshal([]byte(question.TestCases), &testCases)
+	changed := make([]gin.H, 0)
+	for i := range testCases {
+		if i >= len(response.CaseResults) {
+			c.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "评测服务返回的结果数量不足"})
+			return
+		}
+		result := response.CaseResults[i]
+		if result.Status != statusAccepted && result.Status != "Wrong Answer" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("参考答案在测试用例 %d 上运行失败: %s", i+1, result.Status),
+			})
+			return
+		}
+		output := strings.TrimRight(result.Stdout, "\r\n")
+		if fmt.Sprint(testCases[i].ExpectedOutput) != output {
+			changed = append(changed, gin.H{"index": i + 1, "old": testCases[i].ExpectedOutput, "new": output})
+			testCases[i].ExpectedOutput = output
+		}
+	}
+	if err := db.Model(&question).Update("test_cases", toJSONString(testCases)).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存测试用例失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("已更新 %d 个测试用例的期望输出", len(changed)),
+		"data": gin.H{
+			"question_id": question.ID,
+			"changed":     changed,
+			"test_cases":  testCases,
+		},
+	})
+}
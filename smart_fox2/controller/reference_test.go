@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJudgeOutputs 启动一个按给定输出逐个返回用例结果的评测服务，输出与期望一致时判为通过
+func fakeJudgeOutputs(t *testing.T, outputs ...string) *EvaluationRequest {
+	received := &EvaluationRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(received)
+		var resp EvaluationResponse
+		for i, output := range outputs {
+			status := "Wrong Answer"
+			if i < len(received.TestCases) && received.TestCases[i].ExpectedOutput == output {
+				status = statusAccepted
+			}
+			resp.CaseResults = append(resp.CaseResults, EvaluationCaseResult{Status: status, Stdout: output + "\n"})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("JUDGE_URL", srv.URL)
+	return received
+}
+
+func TestCreateExperiment_ValidateTestCases(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+	fakeJudgeOutputs(t, "3", "5")
+
+	teacher := models.User{Name: "teacher", Role: "teacher"}
+	db.Create(&teacher)
+	create := func(mode string) *httptest.ResponseRecorder {
+		body := `{"title":"加法","permission":1,"student_ids":["1"],"deadline":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `",
+			"questions":[{"type":"code","content":"a+b","score":10,
+			"test_cases":[{"input":"1 2","expected_output":"3"},{"input":"2 2","expected_output":"5"},{"input":"2 3","expected_output":"6"}],
+			"reference_solution":{"language":"python","source_code":"print(sum(map(int, input().split())))"},
+			"validate_test_cases":"` + mode + `"}]}`
+		c, w := setupTestContext(teacher)
+		c.Request, _ = http.NewRequest("POST", "/experiments", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CreateExperiment(c)
+		return w
+	}
+
+	w := create("reject")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "参考答案未通过测试用例 3")
+
+	w = create("flag")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Warnings []TestCaseWarning `json:"warnings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if assert.Len(t, resp.Warnings, 1) && assert.Len(t, resp.Warnings[0].Failures, 1) {
+		assert.Equal(t, 3, resp.Warnings[0].Failures[0].Index)
+	}
+
+	// 按 flag 方式校验时题目照常保存
+	var question models.Question
+	db.First(&question, "type = ?", "code")
+	assert.NotNil(t, parseReferenceSolution(question))
+}
+
+func TestRegenerateExpectedOutputs(t *testing.T) {
+	setupTestDBTeacher(t)
+	received := fakeJudgeOutputs(t, "3", "4")
+	teacher := createTestUser(t, "teacher")
+	exp := models.Experiment{
+		ID:         "exp-regen",
+		Title:      "加法",
+		Permission: 1,
+		Deadline:   time.Now().Add(time.Hour),
+		TeacherID:  teacher.ID,
+		Questions: []models.Question{
+			{ID: "q-code", Type: "code", Content: "a+b", Score: 10, CompareMode: compareChecker,
+				Checker:           `{"language":"python","source_code":"import sys"}`,
+				TestCases:         `[{"input":"1 2","expected_output":"3","sample":true},{"input":"2 2","expected_output":"5","weight":2}]`,
+				ReferenceSolution: `{"language":"python","source_code":"print(sum(map(int, input().split())))"}`},
+			{ID: "q-plain", Type: "code", Content: "echo", Score: 5, TestCases: `[{"input":"1","expected_output":"1"}]`},
+		},
+	}
+	global.DB.Create(&exp)
+
+	regenerate := func(questionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}, {Key: "question_id", Value: questionID}}
+		c.Request = httptest.NewRequest("POST", "/expected-outputs", nil)
+		RegenerateExpectedOutputs(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, regenerate("q-plain").Code)
+
+	w := regenerate("q-code")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "已更新 1 个测试用例的期望输出")
+	// 生成期望输出时不调用检查程序
+	assert.Empty(t, received.CompareMode)
+	assert.Nil(t, received.Checker)
+
+	var question models.Question
+	global.DB.First(&question, "id = ?", "q-code")
+	var testCases []TestCase
+	json.Unmarshal([]byte(question.TestCases), &testCases)
+	if assert.Len(t, testCases, 2) {
+		assert.Equal(t, "3", testCases[0].ExpectedOutput)
+		assert.True(t, testCases[0].Sample)
+		assert.Equal(t, "4", testCases[1].ExpectedOutput)
+		assert.Equal(t, 2.0, testCases[1].Weight)
+	}
+}
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RubricCriterion 评分细则中的一项评分标准，按所选等级给分
+type RubricCriterion struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Levels      []RubricLevel `json:"levels"`
+}
+
+// RubricLevel 评分标准的一个等级
+type RubricLevel struct {
+	Label       string `json:"label"`
+	Points      int    `json:"points"`
+	Description string `json:"description,omitempty"`
+}
+
+// RubricGrade 某项评分标准的批阅结果，学生可见
+type RubricGrade struct {
+	Criterion string `json:"criterion"`
+	Level     string `json:"level"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// maxPoints 评分标准的满分，即最高等级的分数
+func (criterion RubricCriterion) maxPoints() int {
+	max := 0
+	for _, level := range criterion.Levels {
+		if level.Points > max {
+			max = level.Points
+		}
+	}
+	return max
+}
+
+// parseRubric 读取题目的评分细则，未设置时返回 nil
+func parseRubric(question models.Question) []RubricCriterion {
+	var rubric []RubricCriterion
+	if question.Rubric == "" || json.Unmarshal([]byte(question.Rubric), &rubric) != nil {
+		return nil
+	}
+	return rubric
+}
+
+// rubricTotal 评分细则部分的满分
+func rubricTotal(question models.Question) int {
+	total := 0
+	for _, criterion := range parseRubric(question) {
+		total += criterion.maxPoints()
+	}
+	return total
+}
+
+// autoGradedScore 代码题中由测试用例自动评分的分值，为题目分值减去评分细则部分
+func autoGradedScore(question models.Question) int {
+	return question.Score - rubricTotal(question)
+}
+
+// autoPartScore 答案中自动评分部分的得分，不受教师改分和评分细则批阅的影响。
+// 代码题按保存的评测明细重新计算；没有评测明细的历史答案按改分前的得分扣除评分细则部分估算
+func autoPartScore(question models.Question, answer models.QuestionSubmission) int {
+	if question.Type != "code" {
+		return 0 // 主观题没有自动评分部分
+	}
+	score := 0
+	if result := parseJudgeResult(answer); result != nil {
+		score = judgeResultScore(question, *result)
+	} else {
+		base := answer.Score
+		if answer.OverrideReason != "" && answer.AutoScore != nil {
+			base = *answer.AutoScore
+		}
+		score = base - answer.RubricScore
+	}
+	return max(0, min(score, autoGradedScore(question)))
+}
+
+// parseRubricGrades 读取单题答案的评分细则批阅结果
+func parseRubricGrades(answer models.QuestionSubmission) []RubricGrade {
+	var grades []RubricGrade
+	if answer.RubricGrades == "" || json.Unmarshal([]byte(answer.RubricGrades), &grades) != nil {
+		return nil
+	}
+	return grades
+}
+
+// rubricJSON 校验评分细则并序列化，只有代码题和主观题可以设置，细则满分不能超过题目分值
+func rubricJSON(questionType string, score int, rubric []RubricCriterion) (string, error) {
+	if len(rubric) == 0 {
+		return "", nil
+	}
+	if questionType != "code" && questionType != "essay" {
+		return "", errors.New("只有代码题和主观题可以设置评分细则")
+	}
+	total := 0
+	names := make(map[string]bool)
+	for _, criterion := range rubric {
+		if criterion.Name == "" || len(criterion.Levels) == 0 {
+			return "", errors.New("评分标准需要名称和至少一个等级")
+		}
+		if names[criterion.Name] {
+			return "", fmt.Errorf("评分标准 %s 重复", criterion.Name)
+		}
+		names[criterion.Name] = true
+		labels := make(map[string]bool)
+		for _, level := range criterion.Levels {
+			if level.Label == "" || level.Points < 0 {
+				return "", fmt.Errorf("评分标准 %s 的等级需要名称且分数不能为负", criterion.Name)
+			}
+			if labels[level.Label] {
+				return "", fmt.Errorf("评分标准 %s 的等级 %s 重复", criterion.Name, level.Label)
+			}
+			labels[level.Label] = true
+		}
+		total += criterion.maxPoints()
+	}
+	if total > score {
+		return "", fmt.Errorf("评分细则满分 %d 超过题目分值 %d", total, score)
+	}
+	return toJSONString(rubric), nil
+}
+
+// GradeRubric 教师按评分细则批阅代码题或主观题，该题得分为自动评分部分加评分细则部分
+func GradeRubric(c *gin.Context) {
+	var req struct {
+		Criteria []struct {
+			Name    string `json:"name" binding:"required"`
+			Level   string `json:"level" binding:"required"`
+			Comment string `json:"comment"`
+		} `json:"criteria" binding:"required,min=1,dive"`
+		Comment *string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "请求参数错误: " + err.Error()})
+		return
+	}
+	db := global.DB
+	answer, ok := loadSubmittedAnswer(c, db)
+	if !ok {
+		return
+	}
+	var question models.Question
+	if err := db.First(&question, "id = ?", answer.QuestionID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "题目不存在"})
+		return
+	}
+	rubric := parseRubric(question)
+	if len(rubric) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "该题目没有设置评分细则"})
+		return
+	}
+
+	// 每项评分标准都必须选择一个等级
+	selected := make(map[string]int, len(req.Criteria))
+	for i, item := range req.Criteria {
+		selected[item.Name] = i
+	}
+	grades := make([]RubricGrade, 0, len(rubric))
+	rubricScore := 0
+	for _, criterion := range rubric {
+		i, ok := selected[criterion.Name]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "缺少评分标准 " + criterion.Name + " 的评分"})
+			return
+		}
+		item := req.Criteria[i]
+		grade := RubricGrade{Criterion: criterion.Name, Level: item.Level, MaxPoints: criterion.maxPoints(), Comment: item.Comment}
+		found := false
+		for _, level := range criterion.Levels {
+			if level.Label == item.Level {
+				grade.Points = level.Points
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "评分标准 " + criterion.Name + " 没有等级 " + item.Level})
+			return
+		}
+		rubricScore += grade.Points
+		grades = append(grades, grade)
+	}
+
+	// 按细则重新批阅时得分为自动评分部分加细则得分，取代之前的细则得分和教师改分；
+	// 改分前的自动评分（auto_score）保留用于审计
+	autoScore := autoPartScore(question, answer)
+	score := min(autoScore+rubricScore, answer.PerfectScore)
+	submission := answer.ExperimentSubmission
+	now := time.Now()
+	updates := map[string]interface{}{
+		"score":           score,
+		"rubric_score":    rubricScore,
+		"rubric_grades":   toJSONString(grades),
+		"review_status":   "graded",
+		"graded_by":       currentUser(c).ID,
+		"graded_at":       now,
+		"override_reason": "",
+		"overridden_by":   nil,
+		"overridden_at":   nil,
+	}
+	if req.Comment != nil {
+		updates["comment"] = *req.Comment
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.QuestionSubmission{}).Where("id = ?", answer.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recomputeSubmissionScore(tx, &submission)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "保存评分失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "评分已保存",
+		"data": gin.H{
+			"answer_id":         answer.ID,
+			"score":             score,
+			"auto_score":        autoScore,
+			"rubric_score":      rubricScore,
+			"rubric":            grades,
+			"submission_id":     submission.ID,
+			"submission_status": submission.Status,
+			"total_score":       submission.TotalScore,
+		},
+	})
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoPartScore(t *testing.T) {
+	rubric, _ := rubricJSON("code", 10, []RubricCriterion{{Name: "风格", Levels: []RubricLevel{{Label: "好", Points: 4}}}})
+	question := models.Question{Type: "code", Score: 10, Rubric: rubric}
+	autoScore := 6
+
+	// 没有评测明细的历史答案：改分后按改分前的得分估算，且不会为负
+	assert.Equal(t, 2, autoPartScore(question, models.QuestionSubmission{Score: 6, RubricScore: 4}))
+	assert.Equal(t, 2, autoPartScore(question, models.QuestionSubmission{Score: 1, RubricScore: 4, AutoScore: &autoScore, OverrideReason: "改分"}))
+	assert.Equal(t, 0, autoPartScore(question, models.QuestionSubmission{Score: 1, RubricScore: 4}))
+	// 主观题没有自动评分部分
+	assert.Equal(t, 0, autoPartScore(models.Question{Type: "essay", Score: 10, Rubric: rubric}, models.QuestionSubmission{Score: 8, RubricScore: 4}))
+}
+
+func TestRubricJSON(t *testing.T) {
+	rubric := []RubricCriterion{
+		{Name: "代码风格", Levels: []RubricLevel{{Label: "优秀", Points: 3}, {Label: "一般", Points: 1}}},
+		{Name: "注释文档", Levels: []RubricLevel{{Label: "完整", Points: 2}, {Label: "缺失", Points: 0}}},
+	}
+	data, err := rubricJSON("code", 10, rubric)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, rubricTotal(models.Question{Score: 10, Rubric: data}))
+	assert.Equal(t, 5, autoGradedScore(models.Question{Score: 10, Rubric: data}))
+
+	_, err = rubricJSON("code", 4, rubric)
+	assert.Error(t, err, "细则满分超过题目分值")
+	_, err = rubricJSON("choice", 10, rubric)
+	assert.Error(t, err)
+	_, err = rubricJSON("essay", 10, append(rubric, rubric[0]))
+	assert.Error(t, err, "评分标准重复")
+
+	data, err = rubricJSON("code", 10, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+// fakeJudge 启动一个评测服务，前 passed 个用例通过，其余答案错误
+func fakeJudge(t *testing.T, passed, total int) {
+	statuses := make([]string, total)
+	for i := range statuses {
+		statuses[i] = "Wrong Answer"
+		if i < passed {
+			statuses[i] = statusAccepted
+		}
+	}
+	fakeJudgeStatuses(t, statuses...)
+}
+
+// fakeJudgeStatuses 启动一个按给定状态逐个返回用例结果的评测服务
+func fakeJudgeStatuses(t *testing.T, statuses ...string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp EvaluationResponse
+		passed, total := 0, len(statuses)
+		for _, status := range statuses {
+			resp.CaseResults = append(resp.CaseResults, EvaluationCaseResult{Status: status})
+			if status == statusAccepted {
+				passed++
+			}
+		}
+		resp.Summary.TotalCases = total
+		resp.Summary.PassedCases = passed
+		resp.Summary.PassRate = float64(passed) * 100 / float64(total)
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("JUDGE_URL", srv.URL)
+}
+
+func TestGradeRubric_CombinesWithTestCases(t *testing.T) {
+	setupTestDBTeacher(t)
+	fakeJudge(t, 1, 2)
+	teacher := createTestUser(t, "teacher")
+	stu := createTestUser(t, "student")
+	rubric, _ := rubricJSON("code", 10, []RubricCriterion{
+		{Name: "代码风格", Levels: []RubricLevel{{Label: "优秀", Points: 2}, {Label: "一般", Points: 1}}},
+		{Name: "注释文档", Levels: []RubricLevel{{Label: "完整", Points: 2}, {Label: "缺失", Points: 0}}},
+	})
+	exp := models.Experiment{
+		ID:         "exp-rubric",
+		Title:      "排序实验",
+		Permission: 1,
+		Deadline:   time.Now().Add(time.Hour),
+		TeacherID:  teacher.ID,
+		Users:      []models.User{stu},
+		Questions: []models.Question{
+			{ID: "q-code", Type: "code", Content: "实现排序", Score: 10, Rubric: rubric,
+				TestCases: `[{"input":"1","expected_output":"1"},{"input":"2","expected_output":"2"}]`},
+		},
+	}
+	global.DB.Create(&exp)
+
+	body := `{"answers":[{"question_id":"q-code","type":"code","code":"print(1)","language":"python"}]}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request = httptest.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	// 测试用例部分满分为 10-4=6，通过一半得 3 分
+	assert.Contains(t, w.Body.String(), `"total_score":"3/10"`)
+	assert.Contains(t, w.Body.String(), `"submission_status":"pending_review"`)
+
+	var answer models.QuestionSubmission
+	global.DB.First(&answer, "question_id = ?", "q-code")
+
+	grade := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}, {Key: "answer_id", Value: answer.ID}}
+		c.Request = httptest.NewRequest("PUT", "/rubric", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		GradeRubric(c)
+		return w
+	}
+	assert.Equal(t, http.StatusBadRequest, grade(`{"criteria":[{"name":"代码风格","level":"优秀"}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, grade(`{"criteria":[{"name":"代码风格","level":"满分"},{"name":"注释文档","level":"完整"}]}`).Code)
+
+	w = grade(`{"criteria":[{"name":"代码风格","level":"优秀"},{"name":"注释文档","level":"缺失","comment":"缺少函数注释"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"submission_status":"graded"`)
+
+	// 重新批阅时替换原有细则得分
+	w = grade(`{"criteria":[{"name":"代码风格","level":"一般"},{"name":"注释文档","level":"完整"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	global.DB.First(&answer, "id = ?", answer.ID)
+	assert.Equal(t, 6, answer.Score)
+	assert.Equal(t, 3, answer.RubricScore)
+
+	// 教师改分后再按细则批阅，自动评分部分仍按评测结果计算，改分被新的批阅取代
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}, {Key: "answer_id", Value: answer.ID}}
+	c.Request = httptest.NewRequest("PUT", "/score", bytes.NewBufferString(`{"score":1,"reason":"抄袭"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	OverrideAnswerScore(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = grade(`{"criteria":[{"name":"代码风格","level":"一般"},{"name":"注释文档","level":"完整"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"auto_score":3`)
+	global.DB.First(&answer, "id = ?", answer.ID)
+	assert.Equal(t, 6, answer.Score)
+	assert.Empty(t, answer.OverrideReason)
+	assert.Nil(t, answer.OverriddenAt)
+
+	// 学生在提交记录中看到各项评分
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Request = httptest.NewRequest("GET", "/submissions", nil)
+	GetSubmissions(c)
+	var resp struct {
+		Data []struct {
+			TotalScore int `json:"total_score"`
+			Results    []struct {
+				Rubric []RubricGrade `json:"rubric"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if assert.Len(t, resp.Data, 1) && assert.Len(t, resp.Data[0].Results, 1) {
+		assert.Equal(t, 6, resp.Data[0].TotalScore)
+		assert.Len(t, resp.Data[0].Results[0].Rubric, 2)
+		assert.Equal(t, "一般", resp.Data[0].Results[0].Rubric[0].Level)
+	}
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"lh/common"
+	"lh/models"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 辅助函数：学生在实验中的打乱种子，同一学生的多次作答沿用同一个种子，保证看到的顺序稳定
+func shuffleSeedFor(db *gorm.DB, experimentID string, studentID uint) int64 {
+	var previous models.ExperimentSubmission
+	if err := db.Where("experiment_id = ? AND student_id = ? AND shuffle_seed <> 0", experimentID, studentID).
+		First(&previous).Error; err == nil {
+		return previous.ShuffleSeed
+	}
+	seed := rand.Int63()
+	for seed == 0 {
+		seed = rand.Int63()
+	}
+	return seed
+}
+
+// shuffleKey 由种子和题目 ID 生成稳定的哈希值，题目增删不影响其余题目的相对顺序
+func shuffleKey(seed int64, id string) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%s", seed, id)
+	return h.Sum64()
+}
+
+// shuffledQuestions 按种子打乱题目顺序，返回新切片，不修改原顺序
+func shuffledQuestions(questions []models.Question, seed int64) []models.Question {
+	shuffled := make([]models.Question, len(questions))
+	copy(shuffled, questions)
+	sort.SliceStable(shuffled, func(i, j int) bool {
+		return shuffleKey(seed, shuffled[i].ID) < shuffleKey(seed, shuffled[j].ID)
+	})
+	return shuffled
+}
+
+// optionOrder 返回打乱后第 i 个选项在原始选项中的下标
+func optionOrder(questionID string, n int, seed int64) []int {
+	r := rand.New(rand.NewSource(int64(shuffleKey(seed, questionID))))
+	return r.Perm(n)
+}
+
+// shuffledOptions 按种子打乱选择题选项
+func shuffledOptions(question models.Question, seed int64) []string {
+	options := common.ParseJSONArray(question.Options)
+	shuffled := make([]string, len(options))
+	for i, idx := range optionOrder(question.ID, len(options), seed) {
+		shuffled[i] = options[idx]
+	}
+	return shuffled
+}
+
+// optionLabelIndex 把 A、B、C 这样的选项字母转换为下标，不是字母时返回 -1
+func optionLabelIndex(label string, n int) int {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	if len(label) != 1 || label[0] < 'A' || int(label[0]-'A') >= n {
+		return -1
+	}
+	return int(label[0] - 'A')
+}
+
+// canonicalChoiceAnswer 把学生在打乱后的选项中的选择映射回原始选项文本
+// 学生提交选项文本时原样返回；提交选项字母时按打乱后的顺序换算为原始选项文本
+func canonicalChoiceAnswer(question models.Question, answer string, seed int64, shuffled bool) string {
+	options := common.ParseJSONArray(question.Options)
+	for _, option := range options {
+		if option == answer {
+			return answer
+		}
+	}
+	idx := optionLabelIndex(answer, len(options))
+	if idx < 0 {
+		return answer
+	}
+	if shuffled {
+		idx = optionOrder(question.ID, len(options), seed)[idx]
+	}
+	return options[idx]
+}
+
+// choiceAnswerCorrect 判断选择题答案是否正确，正确答案和学生答案都可以是选项文本或原始顺序下的选项字母
+func choiceAnswerCorrect(question models.Question, answer string) bool {
+	if answer == question.CorrectAnswer {
+		return true
+	}
+	options := common.ParseJSONArray(question.Options)
+	if idx := optionLabelIndex(question.CorrectAnswer, len(options)); idx >= 0 && options[idx] == answer {
+		return true
+	}
+	if idx := optionLabelIndex(answer, len(options)); idx >= 0 && options[idx] == question.CorrectAnswer {
+		return true
+	}
+	return false
+}
+
+// newDraftSubmission 创建作答草稿，同时固定学生的打乱种子和抽题结果
+func newDraftSubmission(db *gorm.DB, experiment models.Experiment, studentID uint, now time.Time) models.ExperimentSubmission {
+	seed := shuffleSeedFor(db, experiment.ID, studentID)
+	return models.ExperimentSubmission{
+		ID:             uuid.New().String(),
+		ExperimentID:   experiment.ID,
+		StudentID:      studentID,
+		Status:         "in_progress",
+		ShuffleSeed:    seed,
+		DrawnQuestions: drawnQuestionsFor(db, experiment.ID, studentID, seed),
+		SubmittedAt:    now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// 辅助函数：学生的抽题结果，沿用该学生之前作答时的抽题，保证多次作答题目一致
+func drawnQuestionsFor(db *gorm.DB, experimentID string, studentID uint, seed int64) string {
+	var previous models.ExperimentSubmission
+	if err := db.Where("experiment_id = ? AND student_id = ? AND drawn_questions <> ''", experimentID, studentID).
+		First(&previous).Error; err == nil {
+		return previous.DrawnQuestions
+	}
+	var pools []models.QuestionPool
+	var questions []models.Question
+	if err := db.Where("experiment_id = ?", experimentID).Find(&pools).Error; err != nil || len(pools) == 0 {
+		return ""
+	}
+	if err := db.Where("experiment_id = ?", experimentID).Order("created_at").Find(&questions).Error; err != nil {
+		return ""
+	}
+	return toJSONString(drawQuestionIDs(questions, pools, seed))
+}
+
+// drawQuestionIDs 按抽题规则为学生抽题，不属于任何规则所用题目池的题目所有学生都要作答，返回的 ID 保持原始题目顺序
+func drawQuestionIDs(questions []models.Question, pools []models.QuestionPool, seed int64) []string {
+	pooled := make(map[string]bool, len(pools))
+	for _, rule := range pools {
+		pooled[rule.Pool] = true
+	}
+	taken := make(map[string]bool)
+	for _, q := range questions {
+		if !pooled[q.Pool] {
+			taken[q.ID] = true
+		}
+	}
+	for _, rule := range pools {
+		var candidates []models.Question
+		for _, q := range questions {
+			if q.Pool == rule.Pool && !taken[q.ID] && (rule.Difficulty == "" || q.Difficulty == rule.Difficulty) {
+				candidates = append(candidates, q)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return shuffleKey(seed, "draw:"+candidates[i].ID) < shuffleKey(seed, "draw:"+candidates[j].ID)
+		})
+		for i := 0; i < rule.DrawCount && i < len(candidates); i++ {
+			taken[candidates[i].ID] = true
+		}
+	}
+	drawn := make([]string, 0, len(taken))
+	for _, q := range questions {
+		if taken[q.ID] {
+			drawn = append(drawn, q.ID)
+		}
+	}
+	return drawn
+}
+
+// drawnQuestionSet 学生抽到的题目集合，返回 nil 表示作答全部题目
+func drawnQuestionSet(submission models.ExperimentSubmission) map[string]bool {
+	if submission.DrawnQuestions == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, id := range common.ParseJSONArray(submission.DrawnQuestions) {
+		set[id] = true
+	}
+	return set
+}
+
+// studentQuestions 学生实际需要作答的题目
+func studentQuestions(questions []models.Question, submission models.ExperimentSubmission) []models.Question {
+	set := drawnQuestionSet(submission)
+	if set == nil {
+		return questions
+	}
+	result := make([]models.Question, 0, len(set))
+	for _, q := range questions {
+		if set[q.ID] {
+			result = append(result, q)
+		}
+	}
+	return result
+}
+
+// validatePools 校验抽题规则，每条规则的题目池中都要有足够的题目。
+// 同一题目池的规则共用已抽到的题目，范围重叠时能否抽满取决于抽题顺序，因此不允许重叠：
+// 同一题目池的多条规则必须各自指定不同的难度
+func validatePools(questions []models.Question, pools []models.QuestionPool) error {
+	for i, rule := range pools {
+		for _, prev := range pools[:i] {
+			if prev.Pool == rule.Pool && (prev.Difficulty == "" || rule.Difficulty == "" || prev.Difficulty == rule.Difficulty) {
+				return fmt.Errorf("题目池 %s 的抽题规则范围重叠，同一题目池的多条规则须指定不同难度", rule.Pool)
+			}
+		}
+		available := 0
+		for _, q := range questions {
+			if q.Pool == rule.Pool && (rule.Difficulty == "" || q.Difficulty == rule.Difficulty) {
+				available++
+			}
+		}
+		if available < rule.DrawCount {
+			return fmt.Errorf("题目池 %s 中符合条件的题目不足 %d 道", rule.Pool, rule.DrawCount)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuffledQuestions_StablePerSeed(t *testing.T) {
+	questions := []models.Question{{ID: "q1"}, {ID: "q2"}, {ID: "q3"}, {ID: "q4"}, {ID: "q5"}}
+	first := shuffledQuestions(questions, 42)
+	second := shuffledQuestions(questions, 42)
+	assert.Equal(t, first, second)
+	assert.Len(t, first, len(questions))
+	// 原始顺序不受影响
+	assert.Equal(t, "q1", questions[0].ID)
+}
+
+func TestCanonicalChoiceAnswer(t *testing.T) {
+	question := models.Question{ID: "q1", Type: "choice", Options: `["红","绿","蓝","黄"]`, CorrectAnswer: "蓝"}
+	shuffled := shuffledOptions(question, 7)
+	for i, option := range shuffled {
+		label := string(rune('A' + i))
+		assert.Equal(t, option, canonicalChoiceAnswer(question, label, 7, true))
+		assert.Equal(t, option, canonicalChoiceAnswer(question, option, 7, true))
+	}
+	assert.Equal(t, "红", canonicalChoiceAnswer(question, "A", 7, false))
+
+	// 正确答案以选项字母保存的旧题目
+	question.CorrectAnswer = "C"
+	assert.True(t, choiceAnswerCorrect(question, "蓝"))
+	assert.False(t, choiceAnswerCorrect(question, "红"))
+}
+
+func TestSubmitExperiment_ShuffledOptionLabel(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:               uuid.New().String(),
+		Title:            "Shuffled",
+		Permission:       1,
+		Deadline:         time.Now().Add(24 * time.Hour),
+		ShuffleQuestions: true,
+		ShuffleOptions:   true,
+		Users:            []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "choice", Content: "颜色", Options: `["红","绿","蓝","黄"]`, CorrectAnswer: "蓝", Score: 5},
+		},
+	}
+	db.Create(&exp)
+
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	GetExperimentDetail_Student(c)
+	var detail struct {
+		Data struct {
+			Questions []struct {
+				QuestionID string   `json:"question_id"`
+				Options    []string `json:"options"`
+			} `json:"questions"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &detail)
+	assert.Len(t, detail.Data.Questions, 1)
+	options := detail.Data.Questions[0].Options
+	assert.ElementsMatch(t, []string{"红", "绿", "蓝", "黄"}, options)
+
+	// 按学生看到的顺序用选项字母作答
+	label := ""
+	for i, option := range options {
+		if option == "蓝" {
+			label = string(rune('A' + i))
+		}
+	}
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"choice","answer":"` + label + `"}]}`
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"total_score":"5/5"`), w.Body.String())
+
+	var saved models.QuestionSubmission
+	db.Where("question_id = ?", exp.Questions[0].ID).First(&saved)
+	assert.Equal(t, "蓝", saved.Answer)
+}
+
+func TestDrawQuestionIDs(t *testing.T) {
+	questions := []models.Question{
+		{ID: "fixed", Type: "blank"},
+		{ID: "e1", Pool: "基础", Difficulty: "easy"},
+		{ID: "e2", Pool: "基础", Difficulty: "easy"},
+		{ID: "e3", Pool: "基础", Difficulty: "easy"},
+		{ID: "h1", Pool: "基础", Difficulty: "hard"},
+		{ID: "h2", Pool: "基础", Difficulty: "hard"},
+	}
+	pools := []models.QuestionPool{
+		{Pool: "基础", Difficulty: "easy", DrawCount: 2},
+		{Pool: "基础", Difficulty: "hard", DrawCount: 1},
+	}
+
+	drawn := drawQuestionIDs(questions, pools, 42)
+	assert.Len(t, drawn, 4)
+	assert.Equal(t, "fixed", drawn[0])
+	assert.Equal(t, drawn, drawQuestionIDs(questions, pools, 42))
+
+	easy, hard := 0, 0
+	for _, id := range drawn {
+		switch id[0] {
+		case 'e':
+			easy++
+		case 'h':
+			hard++
+		}
+	}
+	assert.Equal(t, 2, easy)
+	assert.Equal(t, 1, hard)
+
+	assert.NoError(t, validatePools(questions, pools))
+	assert.Error(t, validatePools(questions, []models.QuestionPool{{Pool: "基础", Difficulty: "hard", DrawCount: 3}}))
+	// 同一题目池的规则范围重叠时拒绝
+	assert.Error(t, validatePools(questions, []models.QuestionPool{
+		{Pool: "基础", Difficulty: "easy", DrawCount: 1},
+		{Pool: "基础", Difficulty: "easy", DrawCount: 1},
+	}))
+	assert.Error(t, validatePools(questions, []models.QuestionPool{
+		{Pool: "基础", DrawCount: 1},
+		{Pool: "基础", Difficulty: "hard", DrawCount: 1},
+	}))
+}
+
+func TestSubmitExperiment_DrawnQuestions(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:         uuid.New().String(),
+		Title:      "Pools",
+		Permission: 1,
+		Deadline:   time.Now().Add(24 * time.Hour),
+		Users:      []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5, Pool: "算术"},
+			{ID: uuid.New().String(), Type: "blank", Content: "2+2", CorrectAnswer: "4", Score: 5, Pool: "算术"},
+			{ID: uuid.New().String(), Type: "blank", Content: "3+3", CorrectAnswer: "6", Score: 5, Pool: "算术"},
+		},
+		Pools: []models.QuestionPool{{Pool: "算术", DrawCount: 1}},
+	}
+	db.Create(&exp)
+
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	GetExperimentDetail_Student(c)
+	var detail struct {
+		Data struct {
+			Questions []struct {
+				QuestionID string `json:"question_id"`
+				Content    string `json:"content"`
+			} `json:"questions"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &detail)
+	assert.Len(t, detail.Data.Questions, 1)
+
+	drawnID := detail.Data.Questions[0].QuestionID
+	answers := map[string]string{}
+	var otherID string
+	for _, q := range exp.Questions {
+		answers[q.ID] = q.CorrectAnswer
+		if q.ID != drawnID {
+			otherID = q.ID
+		}
+	}
+
+	// 未抽到的题目不能作答
+	body := `{"answers":[{"question_id":"` + otherID + `","type":"blank","answer":"` + answers[otherID] + `"}]}`
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body = `{"answers":[{"question_id":"` + drawnID + `","type":"blank","answer":"` + answers[drawnID] + `"}]}`
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"total_score":"5/5"`), w.Body.String())
+}
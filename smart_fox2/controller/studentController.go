@@ -9,6 +9,8 @@ import (
 	"lh/common"
 	"lh/global"
 	"lh/models"
+	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -41,10 +43,8 @@ func GetExperiments_Student(c *gin.Context) {
 	studentID := user.(models.User).ID
 	now := time.Now()
 
-	var experimentIDs []string
-	if err := db.Table("experiment_users").
-		Where("user_id = ?", studentID).
-		Pluck("experiment_id", &experimentIDs).Error; err != nil {
+	experimentIDs, err := getAssignedExperimentIDs(db, studentID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Failed to get assigned experiments",
@@ -66,16 +66,13 @@ func GetExperiments_Student(c *gin.Context) {
 		return
 	}
 	query := db.Model(&models.Experiment{}).Where("id IN (?)", experimentIDs)
-
-	// 状态筛选逻辑
-
-	switch status {
-	case "active":
-		query = query.Where("deadline > ?", now)
-	case "expired":
-		query = query.Where("deadline <= ?", now)
+	if courseID := c.Query("course_id"); courseID != "" {
+		query = query.Where("course_id = ?", courseID)
 	}
 
+	// 状态筛选逻辑，按学生的个人截止时间判断是否已过期
+	query = filterStudentExperimentStatus(db, query, status, studentID, now)
+
 	var experiments []models.Experiment
 	var total int64
 	query.Count(&total)
@@ -100,19 +97,24 @@ func GetExperiments_Student(c *gin.Context) {
 		if err := db.Where("experiment_id = ? AND student_id = ?", exp.ID, studentID).Order("created_at DESC").
 			First(&submission).Error; err == nil {
 			submissionStatus = strings.ToLower(submission.Status)
+			// 列表中不评分，作答时间已到的草稿在打开实验或提交时自动提交
+			if !submissionFinished(submission.Status) && attemptTimeUp(submission, now) {
+				submissionStatus = "time_up"
+			}
 		}
 
 		// 确定实验状态
-		expStatus := "active"
-		if exp.Deadline.Before(now) {
-			expStatus = "expired"
-		}
+		extended := applyStudentExtension(db, &exp, studentID)
+		expStatus := getExperimentStatus(exp.OpensAt, exp.Deadline)
 
 		experimentResponses[i] = gin.H{
 			"experiment_id":     exp.ID,
 			"title":             exp.Title,
 			"description":       exp.Description,
+			"course_id":         exp.CourseID,
+			"opens_at":          formatTimePtr(exp.OpensAt),
 			"deadline":          exp.Deadline.Format(time.RFC3339),
+			"extended":          extended,
 			"status":            expStatus,
 			"submission_status": submissionStatus,
 		}
@@ -130,6 +132,47 @@ func GetExperiments_Student(c *gin.Context) {
 	})
 }
 
+// filterStudentExperimentStatus 按学生视角筛选实验状态，个人延期后的截止时间优先于实验截止时间
+func filterStudentExperimentStatus(db *gorm.DB, query *gorm.DB, status string, studentID uint, now time.Time) *gorm.DB {
+	extended := db.Model(&models.DeadlineExtension{}).
+		Select("experiment_id").
+		Where("student_id = ? AND deadline > ?", studentID, now)
+	switch status {
+	case "upcoming":
+		query = query.Where("opens_at > ?", now)
+	case "active":
+		query = query.Where("(opens_at IS NULL OR opens_at <= ?) AND (deadline > ? OR id IN (?))", now, now, extended)
+	case "expired":
+		query = query.Where("deadline <= ? AND id NOT IN (?)", now, extended)
+	}
+	return query
+}
+
+// getAssignedExperimentIDs 获取分配给学生的实验ID，包括直接分配和通过所在分组分配的实验
+func getAssignedExperimentIDs(db *gorm.DB, studentID uint) ([]string, error) {
+	var directIDs, groupIDs []string
+	if err := db.Table("experiment_users").
+		Where("user_id = ?", studentID).
+		Pluck("experiment_id", &directIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Table("experiment_groups").
+		Joins("JOIN group_students ON group_students.group_id = experiment_groups.group_id").
+		Where("group_students.user_id = ?", studentID).
+		Pluck("experiment_groups.experiment_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	experimentIDs := make([]string, 0, len(directIDs)+len(groupIDs))
+	for _, id := range append(directIDs, groupIDs...) {
+		if !seen[id] {
+			seen[id] = true
+			experimentIDs = append(experimentIDs, id)
+		}
+	}
+	return experimentIDs, nil
+}
+
 // GetExperiment 获取实验详细信息
 func GetExperimentDetail_Student(c *gin.Context) {
 	// 获取实验 ID
@@ -139,27 +182,77 @@ func GetExperimentDetail_Student(c *gin.Context) {
 	experimentID := c.Param("experiment_id")
 	var experiment models.Experiment
 	// 查询实验详情，包括关联的阶段和资源
-	result := db.Preload("Questions").Preload("Attachments").
+	result := db.Preload("Questions").Preload("Attachments").Preload("Pools").
 		Where("ID = ?", experimentID).
 		First(&experiment)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Experiment not found"})
 		return
 	}
+	// 个人延期影响截止时间、限时时长以及答案公布时间
+	extended := applyStudentExtension(db, &experiment, studentID)
+
+	// 未到开放时间，只返回基本信息，不返回题目
+	if experiment.OpensAt != nil && time.Now().Before(*experiment.OpensAt) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"experiment_id":     experiment.ID,
+				"permission":        experiment.Permission,
+				"title":             experiment.Title,
+				"description":       experiment.Description,
+				"status":            "upcoming",
+				"opens_at":          formatTimePtr(experiment.OpensAt),
+				"deadline":          experiment.Deadline.Format(time.RFC3339),
+				"questions":         []gin.H{},
+				"attachments":       []gin.H{},
+				"submission_status": "not_started",
+				"total_score":       0,
+			},
+		})
+		return
+	}
 
 	// 获取学生提交记录
 	var submission models.ExperimentSubmission
 	submissionStatus := "not_started"
 	totalScore := 0
-	if err := global.DB.Where("experiment_id = ? AND student_id = ?", experimentID, studentID).Order("created_at DESC").
-		First(&submission).Error; err == nil {
+	err := global.DB.Where("experiment_id = ? AND student_id = ?", experimentID, studentID).Order("created_at DESC").
+		First(&submission).Error
+	needsDraft := experiment.DurationMinutes > 0 || experiment.ShuffleQuestions || experiment.ShuffleOptions ||
+		len(experiment.Pools) > 0
+	if errors.Is(err, gorm.ErrRecordNotFound) && needsDraft && !submissionClosed(experiment, time.Now()) {
+		// 限时实验首次打开即开始计时，打乱顺序或抽题的实验首次打开即固定种子和抽题结果
+		now := time.Now()
+		submission = newDraftSubmission(db, experiment, studentID, now)
+		startAttemptTimer(&submission, experiment, now)
+		err = global.DB.Create(&submission).Error
+	} else if err == nil && !submissionFinished(submission.Status) {
+		started := startAttemptTimer(&submission, experiment, time.Now())
+		if submission.ShuffleSeed == 0 && needsDraft {
+			submission.ShuffleSeed = shuffleSeedFor(db, experimentID, studentID)
+			started = true
+		}
+		if started {
+			global.DB.Save(&submission)
+		}
+		if err := autoSubmitExpiredAttempt(global.DB, &submission); err != nil {
+			log.Printf("auto submit of submission %s failed: %v", submission.ID, err)
+		}
+	}
+	if err == nil {
 		submissionStatus = strings.ToLower(submission.Status)
 		totalScore = submission.TotalScore
 	}
+	attempts, _ := submittedAttempts(global.DB, experimentID, studentID)
 
-	// 获取学生答案
-	questionResponses := make([]gin.H, len(experiment.Questions))
-	for i, q := range experiment.Questions {
+	// 获取学生答案，只返回学生抽到的题目，并按学生的种子打乱题目和选项顺序
+	questions := studentQuestions(experiment.Questions, submission)
+	if experiment.ShuffleQuestions {
+		questions = shuffledQuestions(questions, submission.ShuffleSeed)
+	}
+	questionResponses := make([]gin.H, len(questions))
+	for i, q := range questions {
 		questionData := gin.H{
 			"question_id": q.ID,
 			"type":        q.Type,
@@ -169,13 +262,39 @@ func GetExperimentDetail_Student(c *gin.Context) {
 		}
 
 		// 选择题添加选项
-		if q.Type == "choice" {
+		if q.Type == "choice" || q.Type == "multi_choice" {
 			var options []string
-			json.Unmarshal([]byte(q.Options), &options)
+			if experiment.ShuffleOptions {
+				options = shuffledOptions(q, submission.ShuffleSeed)
+			} else {
+				json.Unmarshal([]byte(q.Options), &options)
+			}
 			questionData["options"] = options
 		}
-		if experiment.Deadline.Before(time.Now()) {
-			if q.Type != "code" {
+		if q.Type == "numeric" && q.Unit != "" {
+			questionData["unit"] = q.Unit
+		}
+		if config, ok := parseBlankConfig(q); ok && q.Type == "blank" && len(config.Blanks) > 1 {
+			questionData["blank_count"] = len(config.Blanks)
+		}
+		if q.Type == "code" {
+			questionData["samples"] = sampleCases(q)
+			if limits := parseLanguageLimits(q); limits != nil {
+				questionData["language_limits"] = limits
+			}
+			questionData["time_limit"], questionData["memory_limit_mb"] = resourceLimits(q, "")
+			questionData["compare_mode"] = compareMode(q)
+			if q.CompareMode == compareFloat && q.FloatEpsilon > 0 {
+				questionData["float_epsilon"] = q.FloatEpsilon
+			}
+		}
+		if rubric := parseRubric(q); rubric != nil {
+			questionData["rubric"] = rubric
+		}
+		if answersRevealed(experiment, len(attempts), time.Now()) {
+			if q.Type == "multi_choice" {
+				questionData["correct_answer"] = common.ParseJSONArray(q.CorrectAnswer)
+			} else if q.Type != "code" {
 				questionData["correct_answer"] = q.CorrectAnswer
 			}
 			questionData["explanation"] = q.Explanation
@@ -187,13 +306,25 @@ func GetExperimentDetail_Student(c *gin.Context) {
 			if q.Type == "code" {
 				questionData["student_code"] = qSubmission.Code
 				questionData["student_language"] = qSubmission.Language
+			} else if q.Type == "multi_choice" || questionData["blank_count"] != nil {
+				questionData["student_answer"] = common.ParseJSONArray(qSubmission.Answer)
 			} else {
 				questionData["student_answer"] = qSubmission.Answer
 			}
 			// if experiment.Deadline.Before(time.Now()) {
 			questionData["feedback"] = qSubmission.Feedback
+			if grades := parseRubricGrades(qSubmission); grades != nil {
+				questionData["rubric_grades"] = grades
+			}
+			if judgeResult := parseJudgeResult(qSubmission); judgeResult != nil {
+				questionData["judge_result"] = judgeResult
+			}
 			// }
 		}
+		// 学生还没有保存过代码时返回起始代码
+		if starterCode := parseLanguageCode(q.StarterCode); starterCode != nil && q.Type == "code" && qSubmission.Code == "" {
+			questionData["starter_code"] = starterCode
+		}
 		questionResponses[i] = questionData
 	}
 	attachmentResponses := make([]gin.H, len(experiment.Attachments))
@@ -212,11 +343,23 @@ func GetExperimentDetail_Student(c *gin.Context) {
 			"permission":        experiment.Permission,
 			"title":             experiment.Title,
 			"description":       experiment.Description,
+			"status":            getExperimentStatus(experiment.OpensAt, experiment.Deadline),
+			"opens_at":          formatTimePtr(experiment.OpensAt),
 			"deadline":          experiment.Deadline.Format(time.RFC3339),
+			"extended":          extended,
 			"questions":         questionResponses,
 			"attachments":       attachmentResponses,
 			"submission_status": submissionStatus,
 			"total_score":       totalScore,
+			"duration_minutes":  experiment.DurationMinutes,
+			"started_at":        formatTimePtr(submission.StartedAt),
+			"ends_at":           formatTimePtr(submission.EndsAt),
+			"remaining_seconds": remainingSeconds(submission, time.Now()),
+			"max_attempts":      experiment.MaxAttempts,
+			"attempts_used":     len(attempts),
+			"late_policy":       latePolicyResponse(experiment),
+			"scoring_policy":    scoringPolicy(experiment),
+			"effective_score":   effectiveScore(scoringPolicy(experiment), attempts),
 		},
 	})
 }
@@ -228,8 +371,8 @@ func SaveAnswer(c *gin.Context) {
 	studentID := user.(models.User).ID
 	type Answer struct {
 		QuestionID string `json:"question_id" binding:"required"`
-		Type       string `json:"type" binding:"required,oneof=choice blank code"`
-		Answer     string `json:"answer" binding:"required_if=Type choice required_if=Type blank"`
+		Type       string `json:"type" binding:"required,oneof=choice multi_choice blank numeric true_false essay code"`
+		Answer     string `json:"answer" binding:"required_if=Type choice required_if=Type multi_choice required_if=Type blank required_if=Type numeric required_if=Type true_false required_if=Type essay"`
 		Code       string `json:"code" binding:"required_if=Type code"`
 		Language   string `json:"language" binding:"required_if=Type code,oneof=cpp java python"`
 	}
@@ -255,22 +398,49 @@ func SaveAnswer(c *gin.Context) {
 		}
 		return
 	}
+	applyStudentExtension(tx, &experiment, studentID)
+	if experiment.OpensAt != nil && now.Before(*experiment.OpensAt) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Experiment is not open yet"})
+		return
+	}
 	// 处理实验提交记录（保持不变）
 	var submission models.ExperimentSubmission
-	result := tx.Where("experiment_id = ? AND student_id = ? AND status != 'submitted'", experimentID, studentID).
+	result := tx.Where("experiment_id = ? AND student_id = ? AND status NOT IN ?", experimentID, studentID, finishedStatuses).
 		Order("created_at DESC").
 		First(&submission)
 
+	// 个人限时已到时先按已保存的草稿自动提交，即使实验随后也已截止
+	if result.Error == nil && attemptTimeUp(submission, now) {
+		if err := autoSubmitExpiredAttempt(tx, &submission); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to auto-submit expired attempt"})
+			return
+		}
+		tx.Commit()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Time is up, saved answers have been submitted automatically"})
+		return
+	}
+	if submissionClosed(experiment, now) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Experiment deadline has passed"})
+		return
+	}
+
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		submission = models.ExperimentSubmission{
-			ID:           uuid.New().String(),
-			ExperimentID: experimentID,
-			StudentID:    studentID,
-			Status:       "in_progress",
-			SubmittedAt:  now,
-			CreatedAt:    now,
-			UpdatedAt:    now,
+		usedAttempts, err := countSubmittedAttempts(tx, experimentID, studentID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error"})
+			return
 		}
+		if attemptsExhausted(experiment, usedAttempts) {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Maximum number of attempts reached"})
+			return
+		}
+		submission = newDraftSubmission(tx, experiment, studentID, now)
+		startAttemptTimer(&submission, experiment, now)
 		if err := tx.Create(&submission).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create submission"})
@@ -282,6 +452,7 @@ func SaveAnswer(c *gin.Context) {
 		return
 	} else {
 		submission.UpdatedAt = now
+		startAttemptTimer(&submission, experiment, now)
 		if err := tx.Save(&submission).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update submission"})
@@ -310,6 +481,7 @@ func SaveAnswer(c *gin.Context) {
 	for _, q := range validQuestions {
 		validQuestionMap[q.ID] = true
 	}
+	drawn := drawnQuestionSet(submission)
 
 	for _, ans := range req.Answers {
 		if _, exists := validQuestionMap[ans.QuestionID]; !exists {
@@ -320,12 +492,29 @@ func SaveAnswer(c *gin.Context) {
 			})
 			return
 		}
+		if drawn != nil && !drawn[ans.QuestionID] {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("Question %s is not assigned to you", ans.QuestionID),
+			})
+			return
+		}
 		var question models.Question
 		if err := tx.Where("id = ?", ans.QuestionID).First(&question).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to validate questions"})
 			return
 		}
+		if question.Type == "choice" {
+			ans.Answer = canonicalChoiceAnswer(question, ans.Answer, submission.ShuffleSeed, experiment.ShuffleOptions)
+		}
+		if question.Type == "multi_choice" {
+			ans.Answer = canonicalMultiChoiceAnswer(question, ans.Answer, submission.ShuffleSeed, experiment.ShuffleOptions)
+		}
+		if question.Type == "true_false" {
+			ans.Answer = canonicalTrueFalseAnswer(ans.Answer)
+		}
 		var qSubmission models.QuestionSubmission
 		result := tx.Where("submission_id = ? AND question_id = ?", submission.ID, ans.QuestionID).
 			First(&qSubmission)
@@ -333,7 +522,7 @@ func SaveAnswer(c *gin.Context) {
 		if result.Error == nil {
 			// 更新现有记录
 			switch ans.Type {
-			case "choice", "blank":
+			case "choice", "multi_choice", "blank", "numeric", "true_false", "essay":
 				qSubmission.Answer = ans.Answer
 				qSubmission.Code = ""
 				qSubmission.Language = ""
@@ -365,7 +554,7 @@ func SaveAnswer(c *gin.Context) {
 			}
 
 			switch ans.Type {
-			case "choice", "blank":
+			case "choice", "multi_choice", "blank", "numeric", "true_false", "essay":
 				qSubmission.Answer = ans.Answer
 			case "code":
 				qSubmission.Code = ans.Code
@@ -412,6 +601,7 @@ func SaveAnswer(c *gin.Context) {
 			"student_id":      studentID,
 			"experiment_id":   experimentID,
 			"updated_at":      now,
+			"ends_at":         formatTimePtr(submission.EndsAt),
 			"saved_questions": fullSavedQuestions,
 		},
 	})
@@ -430,24 +620,14 @@ func SubmitExperiment(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Experiment not found"})
 		return
 	}
-	totalPerfectScore := 0
-	for _, q := range experiment.Questions {
-		totalPerfectScore += q.Score
-
-	}
-	if experiment.Permission == 0 && time.Now().After(experiment.Deadline) {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Experiment deadline has passed"})
+	applyStudentExtension(db, &experiment, studentID)
+	if experiment.OpensAt != nil && time.Now().Before(*experiment.OpensAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Experiment is not open yet"})
 		return
 	}
 	// 2. 解析请求体中的答案
 	var req struct {
-		Answers []struct {
-			QuestionID string `json:"question_id"`
-			Type       string `json:"type"`
-			Answer     string `json:"answer,omitempty"`
-			Code       string `json:"code,omitempty"`
-			Language   string `json:"language,omitempty"`
-		} `json:"answers"`
+		Answers []submittedAnswer `json:"answers"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -458,19 +638,41 @@ func SubmitExperiment(c *gin.Context) {
 	now := time.Now()
 	// 处理实验提交记录（保持不变）
 	var submission models.ExperimentSubmission
-	result := tx.Where("experiment_id = ? AND student_id = ? AND status != 'submitted'", experimentID, studentID).Order("created_at DESC").
+	result := tx.Where("experiment_id = ? AND student_id = ? AND status NOT IN ?", experimentID, studentID, finishedStatuses).Order("created_at DESC").
 		First(&submission)
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		submission = models.ExperimentSubmission{
-			ID:           uuid.New().String(),
-			ExperimentID: experimentID,
-			StudentID:    studentID,
-			Status:       "in_progress",
-			CreatedAt:    now,
-			UpdatedAt:    now,
-			SubmittedAt:  now,
+	// 个人限时已到，拒绝本次提交，按已保存的草稿自动提交；先于截止检查，避免到时的草稿因实验截止而无法提交
+	if result.Error == nil && attemptTimeUp(submission, now) {
+		if err := autoSubmitExpiredAttempt(tx, &submission); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to auto-submit expired attempt"})
+			return
 		}
+		tx.Commit()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Time is up, saved answers have been submitted automatically"})
+		return
+	}
+	if submissionClosed(experiment, now) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Experiment deadline has passed"})
+		return
+	}
+
+	usedAttempts, err := countSubmittedAttempts(tx, experimentID, studentID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error"})
+		return
+	}
+	if attemptsExhausted(experiment, usedAttempts) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Maximum number of attempts reached"})
+		return
+	}
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		submission = newDraftSubmission(tx, experiment, studentID, now)
+		startAttemptTimer(&submission, experiment, now)
 		if err := tx.Create(&submission).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create submission"})
@@ -490,7 +692,7 @@ func SubmitExperiment(c *gin.Context) {
 	}
 
 	// 3. 处理每道题的提交
-	totalScore := 0
+	totalScore, pendingReview := 0, false
 	results := make([]gin.H, 0, len(req.Answers))
 	validQuestionIDs := make([]string, len(req.Answers))
 	for i, ans := range req.Answers {
@@ -510,10 +712,33 @@ func SubmitExperiment(c *gin.Context) {
 	for _, q := range validQuestions {
 		validQuestionMap[q.ID] = q
 	}
+	// 满分按学生抽到的题目计算
+	drawn := drawnQuestionSet(submission)
+	totalPerfectScore := 0
+	for _, q := range studentQuestions(experiment.Questions, submission) {
+		totalPerfectScore += q.Score
+	}
 
 	for _, ans := range req.Answers {
-
+		if drawn != nil && !drawn[ans.QuestionID] {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("Question %s is not assigned to you", ans.QuestionID),
+			})
+			return
+		}
 		question := validQuestionMap[ans.QuestionID]
+		if question.Type == "choice" {
+			// 按学生看到的选项顺序换算回原始选项后再评分
+			ans.Answer = canonicalChoiceAnswer(question, ans.Answer, submission.ShuffleSeed, experiment.ShuffleOptions)
+		}
+		if question.Type == "multi_choice" {
+			ans.Answer = canonicalMultiChoiceAnswer(question, ans.Answer, submission.ShuffleSeed, experiment.ShuffleOptions)
+		}
+		if question.Type == "true_false" {
+			ans.Answer = canonicalTrueFalseAnswer(ans.Answer)
+		}
 		var qSubmission models.QuestionSubmission
 		result := tx.Where("submission_id = ? AND question_id = ?", submission.ID, ans.QuestionID).
 			First(&qSubmission)
@@ -521,7 +746,7 @@ func SubmitExperiment(c *gin.Context) {
 		if result.Error == nil {
 			// 更新现有记录
 			switch ans.Type {
-			case "choice", "blank":
+			case "choice", "multi_choice", "blank", "numeric", "true_false", "essay":
 				qSubmission.Answer = ans.Answer
 				qSubmission.Code = ""
 				qSubmission.Language = ""
@@ -531,7 +756,11 @@ func SubmitExperiment(c *gin.Context) {
 				qSubmission.Answer = ""
 			}
 			qSubmission.UpdatedAt = now
-			qSubmission.Score, qSubmission.Feedback = getScore(question, ans)
+			qSubmission.Score, qSubmission.Feedback, qSubmission.JudgeResult = gradeAnswer(question, ans)
+			qSubmission.ReviewStatus = reviewStatusFor(question)
+			if qSubmission.ReviewStatus != "" {
+				pendingReview = true
+			}
 			totalScore += qSubmission.Score
 			results = append(results, gin.H{
 				"question_id": ans.QuestionID,
@@ -560,13 +789,17 @@ func SubmitExperiment(c *gin.Context) {
 			}
 
 			switch ans.Type {
-			case "choice", "blank":
+			case "choice", "multi_choice", "blank", "numeric", "true_false", "essay":
 				qSubmission.Answer = ans.Answer
 			case "code":
 				qSubmission.Code = ans.Code
 				qSubmission.Language = ans.Language
 			}
-			qSubmission.Score, qSubmission.Feedback = getScore(question, ans)
+			qSubmission.Score, qSubmission.Feedback, qSubmission.JudgeResult = gradeAnswer(question, ans)
+			qSubmission.ReviewStatus = reviewStatusFor(question)
+			if qSubmission.ReviewStatus != "" {
+				pendingReview = true
+			}
 			totalScore += qSubmission.Score
 			results = append(results, gin.H{
 				"question_id": ans.QuestionID,
@@ -584,74 +817,416 @@ func SubmitExperiment(c *gin.Context) {
 			}
 		}
 	}
-	// 5. 更新实验提交记录的总分
-	submission.TotalScore = totalScore
-	submission.Status = "submitted"
+	// 5. 更新实验提交记录的总分，每次提交单独保留为一次尝试
+	submission.SubmittedAt = now
+	submission.RawScore = totalScore
+	submission.TotalScore, submission.LatePenalty = applyLatePenalty(experiment, totalScore, now)
+	submission.Status = submittedStatus(pendingReview)
+	submission.Attempt = usedAttempts + 1
 	if err := tx.Save(&submission).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Failed to save submission",
 		})
+		return
 	}
 	tx.Commit()
+	attempts, _ := submittedAttempts(db, experimentID, studentID)
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data": gin.H{
-			"submission_id": submission.ID,
-			"total_score":   fmt.Sprintf("%d/%d", totalScore, totalPerfectScore),
-			"results":       results,
-			"submitted_at":  submission.SubmittedAt,
+			"submission_id":     submission.ID,
+			"submission_status": submission.Status,
+			"attempt_number":    submission.Attempt,
+			"max_attempts":      experiment.MaxAttempts,
+			"total_score":       fmt.Sprintf("%d/%d", submission.TotalScore, totalPerfectScore),
+			"raw_score":         submission.RawScore,
+			"late_penalty":      submission.LatePenalty,
+			"effective_score":   effectiveScore(scoringPolicy(experiment), attempts),
+			"scoring_policy":    scoringPolicy(experiment),
+			"results":           results,
+			"submitted_at":      submission.SubmittedAt,
 		},
 	})
 }
 
-func getScore(question models.Question, ans struct {
-	QuestionID string "json:\"question_id\""
-	Type       string "json:\"type\""
-	Answer     string "json:\"answer,omitempty\""
-	Code       string "json:\"code,omitempty\""
-	Language   string "json:\"language,omitempty\""
-}) (int, string) {
+// submittedAnswer 学生提交的单题答案
+type submittedAnswer = struct {
+	QuestionID string `json:"question_id"`
+	Type       string `json:"type"`
+	Answer     string `json:"answer,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Language   string `json:"language,omitempty"`
+}
+
+// startAttemptTimer 限时实验在学生首次打开或保存时开始计时，返回是否新开始计时
+func startAttemptTimer(submission *models.ExperimentSubmission, experiment models.Experiment, now time.Time) bool {
+	if experiment.DurationMinutes <= 0 || submission.StartedAt != nil {
+		return false
+	}
+	endsAt := attemptEndsAt(experiment, now)
+	submission.StartedAt = &now
+	submission.EndsAt = &endsAt
+	return true
+}
+
+// attemptEndsAt 计算限时作答的个人截止时间，不超过实验停止接收提交的时间
+func attemptEndsAt(experiment models.Experiment, startedAt time.Time) time.Time {
+	endsAt := startedAt.Add(time.Duration(experiment.DurationMinutes) * time.Minute)
+	if closesAt := submissionClosesAt(experiment); closesAt != nil && endsAt.After(*closesAt) {
+		endsAt = *closesAt
+	}
+	return endsAt
+}
+
+// applyStudentExtension 用学生的个人延期覆盖实验的截止时间和限时作答时长，返回是否存在延期
+func applyStudentExtension(db *gorm.DB, experiment *models.Experiment, studentID uint) bool {
+	var extension models.DeadlineExtension
+	if err := db.Where("experiment_id = ? AND student_id = ?", experiment.ID, studentID).
+		First(&extension).Error; err != nil {
+		return false
+	}
+	if extension.Deadline != nil && extension.Deadline.After(experiment.Deadline) {
+		experiment.Deadline = *extension.Deadline
+	}
+	if extension.TimeMultiplier > 0 && experiment.DurationMinutes > 0 {
+		experiment.DurationMinutes = int(math.Ceil(float64(experiment.DurationMinutes) * extension.TimeMultiplier))
+	}
+	return true
+}
+
+// scoringPolicy 实验的多次提交计分策略，未设置时按最后一次计分
+func scoringPolicy(experiment models.Experiment) string {
+	if experiment.ScoringPolicy == "" {
+		return "last"
+	}
+	return experiment.ScoringPolicy
+}
+
+// submittedAttempts 按提交先后获取学生在实验中的全部已提交尝试
+func submittedAttempts(db *gorm.DB, experimentID string, studentID uint) ([]models.ExperimentSubmission, error) {
+	var attempts []models.ExperimentSubmission
+	err := db.Where("experiment_id = ? AND student_id = ? AND status IN ?", experimentID, studentID, finishedStatuses).
+		Order("attempt ASC, submitted_at ASC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+// countSubmittedAttempts 统计学生在实验中已提交的次数
+func countSubmittedAttempts(db *gorm.DB, experimentID string, studentID uint) (int, error) {
+	var count int64
+	err := db.Model(&models.ExperimentSubmission{}).
+		Where("experiment_id = ? AND student_id = ? AND status IN ?", experimentID, studentID, finishedStatuses).
+		Count(&count).Error
+	return int(count), err
+}
+
+// attemptsExhausted 判断学生是否已用完提交次数
+func attemptsExhausted(experiment models.Experiment, used int) bool {
+	return experiment.MaxAttempts > 0 && used >= experiment.MaxAttempts
+}
+
+// effectiveScore 按计分策略计算有效成绩，没有已提交尝试时返回 0
+func effectiveScore(policy string, attempts []models.ExperimentSubmission) float64 {
+	if len(attempts) == 0 {
+		return 0
+	}
+	switch policy {
+	case "best":
+		best := attempts[0].TotalScore
+		for _, a := range attempts[1:] {
+			if a.TotalScore > best {
+				best = a.TotalScore
+			}
+		}
+		return float64(best)
+	case "average":
+		sum := 0
+		for _, a := range attempts {
+			sum += a.TotalScore
+		}
+		return math.Round(float64(sum)/float64(len(attempts))*100) / 100
+	default:
+		return float64(attempts[len(attempts)-1].TotalScore)
+	}
+}
+
+// submissionClosesAt 实验停止接收提交的时间，不允许迟交时为截止时间，允许迟交时为截止时间加硬性截止时长，返回 nil 表示一直接收
+func submissionClosesAt(experiment models.Experiment) *time.Time {
+	if experiment.Permission == 0 {
+		return &experiment.Deadline
+	}
+	if experiment.LateCutoffHours > 0 {
+		closesAt := experiment.Deadline.Add(time.Duration(experiment.LateCutoffHours) * time.Hour)
+		return &closesAt
+	}
+	return nil
+}
+
+// submissionClosed 判断实验是否已停止接收提交
+func submissionClosed(experiment models.Experiment, now time.Time) bool {
+	closesAt := submissionClosesAt(experiment)
+	return closesAt != nil && now.After(*closesAt)
+}
+
+// answersRevealed 判断是否向学生公布正确答案和解析：实验停止接收提交后公布；
+// 允许迟交且没有硬性截止时间时，只有学生用完提交次数后才公布，避免看过答案再迟交得分
+func answersRevealed(experiment models.Experiment, usedAttempts int, now time.Time) bool {
+	if submissionClosesAt(experiment) != nil {
+		return submissionClosed(experiment, now)
+	}
+	return attemptsExhausted(experiment, usedAttempts)
+}
+
+// latePenaltyPercent 按迟交策略计算扣分百分比，宽限期后每个不足一单位的迟交时长按一单位计
+func latePenaltyPercent(experiment models.Experiment, submittedAt time.Time) float64 {
+	if experiment.LatePenaltyPercent <= 0 {
+		return 0
+	}
+	late := submittedAt.Sub(experiment.Deadline) - time.Duration(experiment.LateGraceMinutes)*time.Minute
+	if late <= 0 {
+		return 0
+	}
+	unit := 24 * time.Hour
+	if experiment.LatePenaltyUnit == "hour" {
+		unit = time.Hour
+	}
+	units := math.Ceil(float64(late) / float64(unit))
+	percent := units * experiment.LatePenaltyPercent
+	if experiment.LateMaxPenalty > 0 && percent > experiment.LateMaxPenalty {
+		percent = experiment.LateMaxPenalty
+	}
+	return math.Min(percent, 100)
+}
+
+// latePolicyResponse 迟交策略的响应数据
+func latePolicyResponse(experiment models.Experiment) gin.H {
+	unit := experiment.LatePenaltyUnit
+	if unit == "" {
+		unit = "day"
+	}
+	return gin.H{
+		"penalty_percent": experiment.LatePenaltyPercent,
+		"penalty_unit":    unit,
+		"grace_minutes":   experiment.LateGraceMinutes,
+		"cutoff_hours":    experiment.LateCutoffHours,
+		"max_penalty":     experiment.LateMaxPenalty,
+		"closes_at":       formatTimePtr(submissionClosesAt(experiment)),
+	}
+}
+
+// applyLatePenalty 对原始得分应用迟交扣分，返回扣分后的总分和扣除的分数
+func applyLatePenalty(experiment models.Experiment, rawScore int, submittedAt time.Time) (int, int) {
+	penalty := int(math.Round(float64(rawScore) * latePenaltyPercent(experiment, submittedAt) / 100))
+	return rawScore - penalty, penalty
+}
+
+// attemptTimeUp 判断限时作答是否已到时
+func attemptTimeUp(submission models.ExperimentSubmission, now time.Time) bool {
+	return submission.EndsAt != nil && now.After(*submission.EndsAt)
+}
+
+// remainingSeconds 限时作答剩余秒数，不限时或已提交时返回 nil
+func remainingSeconds(submission models.ExperimentSubmission, now time.Time) interface{} {
+	if submission.EndsAt == nil || submissionFinished(submission.Status) {
+		return nil
+	}
+	remaining := int(submission.EndsAt.Sub(now).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining
+}
+
+// autoSubmitExpiredAttempt 限时作答到时后，按已保存的草稿评分并自动提交。
+// 评分（代码题需要调用评测服务）在事务之外完成，事务提交成功后才更新 submission
+func autoSubmitExpiredAttempt(db *gorm.DB, submission *models.ExperimentSubmission) error {
+	if submissionFinished(submission.Status) || !attemptTimeUp(*submission, time.Now()) {
+		return nil
+	}
+	var saved []models.QuestionSubmission
+	if err := db.Preload("Question").Where("submission_id = ?", submission.ID).Find(&saved).Error; err != nil {
+		return err
+	}
+	type gradedAnswer struct {
+		score                               int
+		feedback, reviewStatus, judgeResult string
+	}
+	graded := make([]gradedAnswer, len(saved))
+	totalScore, pendingReview := 0, false
+	for i, qs := range saved {
+		score, feedback, judgeResult := gradeAnswer(qs.Question, submittedAnswer{
+			QuestionID: qs.QuestionID,
+			Type:       qs.Type,
+			Answer:     qs.Answer,
+			Code:       qs.Code,
+			Language:   qs.Language,
+		})
+		totalScore += score
+		reviewStatus := reviewStatusFor(qs.Question)
+		if reviewStatus != "" {
+			pendingReview = true
+		}
+		graded[i] = gradedAnswer{score, feedback, reviewStatus, judgeResult}
+	}
+
+	updated := *submission
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, qs := range saved {
+			if err := tx.Model(&models.QuestionSubmission{}).Where("id = ?", qs.ID).
+				Updates(map[string]interface{}{
+					"score":         graded[i].score,
+					"feedback":      graded[i].feedback,
+					"review_status": graded[i].reviewStatus,
+					"judge_result":  graded[i].judgeResult,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		used, err := countSubmittedAttempts(tx, submission.ExperimentID, submission.StudentID)
+		if err != nil {
+			return err
+		}
+		var experiment models.Experiment
+		if err := tx.First(&experiment, "id = ?", submission.ExperimentID).Error; err != nil {
+			return err
+		}
+		applyStudentExtension(tx, &experiment, submission.StudentID)
+		updated.SubmittedAt = *submission.EndsAt
+		updated.RawScore = totalScore
+		updated.TotalScore, updated.LatePenalty = applyLatePenalty(experiment, totalScore, updated.SubmittedAt)
+		updated.Status = submittedStatus(pendingReview)
+		updated.Attempt = used + 1
+		// 只更新仍未提交的记录，避免与同时进行的提交重复计分
+		result := tx.Model(&models.ExperimentSubmission{}).
+			Where("id = ? AND status NOT IN ?", submission.ID, finishedStatuses).
+			Updates(map[string]interface{}{
+				"raw_score":    updated.RawScore,
+				"late_penalty": updated.LatePenalty,
+				"total_score":  updated.TotalScore,
+				"status":       updated.Status,
+				"attempt":      updated.Attempt,
+				"submitted_at": updated.SubmittedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("submission is no longer in progress")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*submission = updated
+	return nil
+}
+
+// finalizeExpiredAttempts 自动提交已到时但仍未提交的限时作答，experimentID 为空时处理全部实验。
+// 教师查看提交和定时清理都会调用，评分不依赖学生再次访问
+func finalizeExpiredAttempts(db *gorm.DB, experimentID string) error {
+	query := db.Where("status NOT IN ? AND ends_at IS NOT NULL AND ends_at < ?", finishedStatuses, time.Now())
+	if experimentID != "" {
+		query = query.Where("experiment_id = ?", experimentID)
+	}
+	var expired []models.ExperimentSubmission
+	if err := query.Find(&expired).Error; err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := autoSubmitExpiredAttempt(db, &expired[i]); err != nil {
+			log.Printf("自动提交到时作答 %s 失败: %v", expired[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// SweepExpiredAttempts 按固定间隔自动提交所有已到时的限时作答
+func SweepExpiredAttempts(db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := finalizeExpiredAttempts(db, ""); err != nil {
+			log.Printf("清理到时作答失败: %v", err)
+		}
+	}
+}
+
+func getScore(question models.Question, ans submittedAnswer) (int, string) {
+	score, feedback, _ := gradeAnswer(question, ans)
+	return score, feedback
+}
+
+// gradeAnswer 自动评分，代码题同时返回各测试用例和子任务的评测明细
+func gradeAnswer(question models.Question, ans submittedAnswer) (int, string, string) {
 	score := 0
+	judgeResult := ""
 	feedback := ""
 	switch question.Type {
-	case "choice", "blank":
-		if ans.Answer == question.CorrectAnswer {
+	case "choice":
+		if choiceAnswerCorrect(question, ans.Answer) {
+			score = question.Score
+			feedback = "Correct"
+		} else {
+			feedback = "Incorrect"
+		}
+	case "multi_choice":
+		score, feedback = multiChoiceScore(question, ans.Answer)
+	case "numeric":
+		if numericAnswerCorrect(question, ans.Answer) {
+			score = question.Score
+			feedback = "Correct"
+		} else {
+			feedback = "Incorrect"
+		}
+	case "true_false":
+		if canonicalTrueFalseAnswer(ans.Answer) == question.CorrectAnswer {
 			score = question.Score
 			feedback = "Correct"
 		} else {
 			feedback = "Incorrect"
 		}
+	case "blank":
+		score, feedback = blankScore(question, ans.Answer)
+	case "essay":
+		// 主观题由教师人工批阅
+		feedback = "Pending manual grading"
 	case "code":
-		// 调用评测服务进行代码评测
-		result, err := evaluateCode(ans.Code, ans.Language, question.TestCases)
+		// 函数题先与测试框架拼接成完整程序，再调用评测服务进行代码评测
+		source, err := assembleSource(question, ans.Code, ans.Language)
+		var result *EvaluationResponse
+		if err == nil {
+			result, err = evaluateCode(source, ans.Language, question)
+		}
 		if err != nil {
 			feedback = fmt.Sprintf("Evaluation error: %v", err)
 		} else {
-			score = int(float64(question.Score) * result.Summary.PassRate / 100)
-			feedback = fmt.Sprintf("Passed %d/%d test cases", result.Summary.PassedCases, result.Summary.TotalCases)
+			// 设置了评分细则时，测试用例只决定自动评分部分
+			var detail JudgeResult
+			score, feedback, detail = codeScore(question, result)
+			judgeResult = toJSONString(detail)
 		}
 	}
-	return score, feedback
+	return score, feedback, judgeResult
 }
 
 // 评测服务请求和响应结构
 type EvaluationRequest struct {
-	Language   string     `json:"language"`
-	SourceCode string     `json:"source_code"`
-	TestCases  []TestCase `json:"test_cases"`
-	TimeLimit  int        `json:"time_limit,omitempty"`
+	Language      string          `json:"language"`
+	SourceCode    string          `json:"source_code"`
+	TestCases     []TestCase      `json:"test_cases"`
+	TimeLimit     int             `json:"time_limit,omitempty"`
+	MemoryLimitMB int             `json:"memory_limit_mb,omitempty"`
+	CompareMode   string          `json:"compare_mode,omitempty"`
+	FloatEpsilon  float64         `json:"float_epsilon,omitempty"`
+	Checker       *CheckerProgram `json:"checker,omitempty"`
 }
 
 type EvaluationResponse struct {
-	CaseResults []struct {
-		Status    string  `json:"status"`
-		Stdout    string  `json:"stdout"`
-		Stderr    string  `json:"stderr"`
-		TimeTaken float64 `json:"time_taken"`
-	} `json:"case_results"`
-	Summary struct {
+	CaseResults []EvaluationCaseResult `json:"case_results"`
+	Summary     struct {
 		TotalCases  int     `json:"total_cases"`
 		PassedCases int     `json:"passed_cases"`
 		PassRate    float64 `json:"pass_rate_percent"`
@@ -659,11 +1234,20 @@ type EvaluationResponse struct {
 	} `json:"summary"`
 }
 
-// evaluateCode 调用评测服务进行代码评测
-func evaluateCode(code, language, testCasesJSON string) (*EvaluationResponse, error) {
+type EvaluationCaseResult struct {
+	Status    string  `json:"status"`
+	Stdout    string  `json:"stdout"`
+	Stderr    string  `json:"stderr"`
+	TimeTaken float64 `json:"time_taken"`
+	// 检查程序给出的提示信息，仅 checker 比较方式返回
+	CheckerMessage string `json:"checker_message"`
+}
+
+// evaluateCode 调用评测服务进行代码评测，按题目和语言设置时间和内存限制
+func evaluateCode(code, language string, question models.Question) (*EvaluationResponse, error) {
 	// 解析测试用例
 	var testCases []TestCase
-	if err := json.Unmarshal([]byte(testCasesJSON), &testCases); err != nil {
+	if err := json.Unmarshal([]byte(question.TestCases), &testCases); err != nil {
 		return nil, fmt.Errorf("invalid test cases format")
 	}
 
@@ -672,8 +1256,11 @@ func evaluateCode(code, language, testCasesJSON string) (*EvaluationResponse, er
 		Language:   language,
 		SourceCode: code,
 		TestCases:  testCases,
-		TimeLimit:  2, // 默认2秒超时
 	}
+	request.TimeLimit, request.MemoryLimitMB = resourceLimits(question, language)
+	request.CompareMode = question.CompareMode
+	request.FloatEpsilon = question.FloatEpsilon
+	request.Checker = parseChecker(question)
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
@@ -760,16 +1347,33 @@ func GetSubmissions(c *gin.Context) {
 	for _, qs := range questionSubmissions {
 		questionSubMap[qs.SubmissionID] = append(questionSubMap[qs.SubmissionID], qs)
 	}
+	// 按实验计算有效成绩，统计范围为全部已提交尝试而非当前分页
+	effectiveScores := make(map[string]float64)
+	usedAttempts := make(map[string]int)
+	for _, sub := range submissions {
+		if _, ok := effectiveScores[sub.ExperimentID]; ok {
+			continue
+		}
+		attempts, err := submittedAttempts(db, sub.ExperimentID, studentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to get attempts"})
+			return
+		}
+		effectiveScores[sub.ExperimentID] = effectiveScore(scoringPolicy(sub.Experiment), attempts)
+		usedAttempts[sub.ExperimentID] = len(attempts)
+	}
 	now := time.Now()
 	// 构建响应
 	submissionResponses := make([]gin.H, len(submissions))
 	for i, sub := range submissions {
+		applyStudentExtension(db, &sub.Experiment, studentID)
+		revealed := answersRevealed(sub.Experiment, usedAttempts[sub.ExperimentID], now)
 		// 获取该提交的问题结果
 		results := make([]gin.H, 0)
 		if qSubs, ok := questionSubMap[sub.ID]; ok {
 			for _, qs := range qSubs {
 				explanation := ""
-				if now.After(sub.Experiment.Deadline) {
+				if revealed {
 					explanation = qs.Question.Explanation
 				}
 				result := gin.H{
@@ -778,6 +1382,14 @@ func GetSubmissions(c *gin.Context) {
 					"score":       qs.Score,
 					"feedback":    qs.Feedback,
 					"explanation": explanation,
+					"comment":     qs.Comment,
+				}
+				if grades := parseRubricGrades(qs); grades != nil {
+					result["rubric_score"] = qs.RubricScore
+					result["rubric"] = grades
+				}
+				if judgeResult := parseJudgeResult(qs); judgeResult != nil {
+					result["judge_result"] = judgeResult
 				}
 				results = append(results, result)
 			}
@@ -788,7 +1400,13 @@ func GetSubmissions(c *gin.Context) {
 			"experiment_id":    sub.ExperimentID,
 			"experiment_title": sub.Experiment.Title,
 			"total_score":      sub.TotalScore,
+			"raw_score":        sub.RawScore,
+			"late_penalty":     sub.LatePenalty,
 			"status":           sub.Status,
+			"attempt_number":   sub.Attempt,
+			"max_attempts":     sub.Experiment.MaxAttempts,
+			"scoring_policy":   scoringPolicy(sub.Experiment),
+			"effective_score":  effectiveScores[sub.ExperimentID],
 			"submitted_at":     sub.SubmittedAt.Format(time.RFC3339),
 			"results":          results,
 		}
@@ -1006,6 +1624,7 @@ func GetStudentNotifications(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 	experimentID := c.Query("experiment_id")
+	courseID := c.Query("course_id")
 	isImportantStr := c.Query("is_important")
 	createdAfter := c.Query("created_after")
 
@@ -1020,16 +1639,25 @@ func GetStudentNotifications(c *gin.Context) {
 		return
 	}
 
-	// Build query with join table
+	// Build query: notifications sent to the student directly or to any group the student is in
+	direct := global.DB.Table("notification_users").
+		Select("notification_id").
+		Where("user_id = ?", studentID)
+	viaGroups := global.DB.Table("notification_groups").
+		Select("notification_groups.notification_id").
+		Joins("JOIN group_students ON group_students.group_id = notification_groups.group_id").
+		Where("group_students.user_id = ?", studentID)
 	query := global.DB.
-		Joins("JOIN notification_users ON notification_users.notification_id = notifications.id").
-		Where("notification_users.user_id = ?", studentID).
+		Where("notifications.id IN (?) OR notifications.id IN (?)", direct, viaGroups).
 		Order("notifications.created_at DESC")
 
 	// Apply filters
 	if experimentID != "" {
 		query = query.Where("notifications.experiment_id = ?", experimentID)
 	}
+	if courseID != "" {
+		query = query.Where("notifications.course_id = ?", courseID)
+	}
 	if isImportantStr != "" {
 		isImportant, err := strconv.ParseBool(isImportantStr)
 		if err == nil {
@@ -1072,6 +1700,34 @@ func GetStudentNotifications(c *gin.Context) {
 	})
 }
 
+// canAccessExperimentFiles 学生只能在实验开放后访问分配给自己的实验文件，管理实验的教师不受限制
+func canAccessExperimentFiles(c *gin.Context, experimentID string) bool {
+	db := global.DB
+	var experiment models.Experiment
+	if err := db.First(&experiment, "id = ?", experimentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return false
+	}
+	user := currentUser(c)
+	if canManageExperiment(db, experiment, user) {
+		return true
+	}
+	assigned, err := isAssignedToExperiment(db, experiment.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check experiment assignment"})
+		return false
+	}
+	if !assigned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Experiment is not assigned to you"})
+		return false
+	}
+	if experiment.OpensAt != nil && time.Now().Before(*experiment.OpensAt) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Experiment is not open yet"})
+		return false
+	}
+	return true
+}
+
 // handleStudentListFilesOSS 列出指定实验下 OSS 中的所有文件
 func HandleStudentListFiles(c *gin.Context) {
 	experimentID := c.Param("experiment_id")
@@ -1079,6 +1735,9 @@ func HandleStudentListFiles(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Experiment ID is required"})
 		return
 	}
+	if !canAccessExperimentFiles(c, experimentID) {
+		return
+	}
 
 	// 构造OSS对象前缀，用于列举
 	prefixToList := fmt.Sprintf("%s%s/", ossExperimentPrefix, experimentID)
@@ -1127,6 +1786,9 @@ func HandleStudentDownloadFile(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Experiment ID and filename are required"})
 		return
 	}
+	if !canAccessExperimentFiles(c, experimentID) {
+		return
+	}
 
 	// 构建完整的OSS对象键
 	// 使用 filepath.Base 清理 filename，增加一层保护
@@ -34,7 +34,7 @@ func setupTestDB() *gorm.DB {
 	// Auto migrate the schema
 	db.AutoMigrate(&models.User{}, &models.Experiment{}, &models.Question{},
 		&models.Attachment{}, &models.ExperimentSubmission{}, &models.QuestionSubmission{},
-		&models.Notification{})
+		&models.Notification{}, &models.Group{}, &models.DeadlineExtension{}, &models.QuestionPool{})
 
 	return db
 }
@@ -567,6 +567,37 @@ func TestHandleStudentDownloadFile_MissingParameters(t *testing.T) {
 	assert.Equal(t, "Experiment ID and filename are required", response["error"])
 }
 
+// 未分配或未开放的实验，学生不能列出或下载文件
+func TestHandleStudentFiles_Forbidden(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+	user := setupTestUser(db)
+	unassigned := setupTestExperiment(db)
+	opensAt := time.Now().Add(time.Hour)
+	upcoming := models.Experiment{ID: uuid.New().String(), Title: "Upcoming", OpensAt: &opensAt,
+		Deadline: time.Now().Add(24 * time.Hour), Users: []models.User{user}}
+	db.Create(&upcoming)
+
+	for _, tc := range []struct {
+		experimentID string
+		message      string
+	}{
+		{unassigned.ID, "Experiment is not assigned to you"},
+		{upcoming.ID, "Experiment is not open yet"},
+	} {
+		for _, handler := range []gin.HandlerFunc{HandleStudentListFiles, HandleStudentDownloadFile} {
+			c, w := setupTestContext(user)
+			c.Params = gin.Params{{Key: "experiment_id", Value: tc.experimentID}, {Key: "filename", Value: "report.pdf"}}
+			handler(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tc.message, response["error"])
+		}
+	}
+}
+
 func TestHandleStudentDownloadFile_ValidParameters(t *testing.T) {
 	// Note: This test would require mocking OSS client
 	// Since OSS is not initialized in test environment, this will panic
@@ -702,7 +733,7 @@ func TestEvaluateCode_InvalidTestCases(t *testing.T) {
 	language := "python"
 	testCasesJSON := "invalid json"
 
-	result, err := evaluateCode(code, language, testCasesJSON)
+	result, err := evaluateCode(code, language, models.Question{TestCases: testCasesJSON})
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -714,7 +745,7 @@ func TestEvaluateCode_ValidTestCases_ServiceUnavailable(t *testing.T) {
 	language := "python"
 	testCasesJSON := `[{"input": "test", "expected_output": "test"}]`
 
-	result, err := evaluateCode(code, language, testCasesJSON)
+	result, err := evaluateCode(code, language, models.Question{TestCases: testCasesJSON})
 
 	// Since the evaluation service is not running in test, we expect an error
 	assert.Error(t, err)
@@ -861,3 +892,440 @@ func TestSubmitExperiment_InvalidRequestBody(t *testing.T) {
 	assert.Equal(t, "error", response["status"])
 	assert.Equal(t, "Invalid request body", response["message"])
 }
+
+// 通过分组分配的实验对后加入分组的学生同样可见
+func TestGetExperimentsStudent_AssignedViaGroup(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	group := models.Group{Name: "第一组"}
+	db.Create(&group)
+	exp := models.Experiment{
+		ID:       uuid.New().String(),
+		Title:    "Group Experiment",
+		Deadline: time.Now().Add(24 * time.Hour),
+		Groups:   []models.Group{group},
+	}
+	db.Create(&exp)
+	db.Create(&models.Notification{ID: "n-group", Title: "分组通知", Content: "内容", Groups: []models.Group{group}})
+
+	// 实验分配之后才把学生加入分组
+	db.Model(&group).Association("Student").Append(&user)
+
+	c, w := setupTestContext(user)
+	c.Request, _ = http.NewRequest("GET", "/experiments", nil)
+	GetExperiments_Student(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, exp.ID, response.Data[0].ExperimentID)
+
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "student_id", Value: strconv.Itoa(int(user.ID))}}
+	c.Request, _ = http.NewRequest("GET", "/notifications", nil)
+	GetStudentNotifications(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var notiResponse struct {
+		Data []models.Notification `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &notiResponse)
+	assert.Len(t, notiResponse.Data, 1)
+}
+
+// 未开放的实验不返回题目，也不能保存或提交答案
+func TestExperimentStudent_Upcoming(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	opensAt := time.Now().Add(7 * 24 * time.Hour)
+	exp := models.Experiment{
+		ID:       uuid.New().String(),
+		Title:    "Upcoming Experiment",
+		OpensAt:  &opensAt,
+		Deadline: time.Now().Add(14 * 24 * time.Hour),
+		Users:    []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "secret", CorrectAnswer: "x", Score: 5},
+		},
+	}
+	db.Create(&exp)
+
+	c, w := setupTestContext(user)
+	c.Request, _ = http.NewRequest("GET", "/experiments?status=upcoming", nil)
+	GetExperiments_Student(c)
+	var listResp struct {
+		Data []struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	assert.Len(t, listResp.Data, 1)
+	assert.Equal(t, "upcoming", listResp.Data[0].Status)
+
+	c, w = setupTestContext(user)
+	c.Request, _ = http.NewRequest("GET", "/experiments?status=active", nil)
+	GetExperiments_Student(c)
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	assert.Len(t, listResp.Data, 0)
+
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	GetExperimentDetail_Student(c)
+	var detailResp struct {
+		Data struct {
+			Status    string        `json:"status"`
+			Questions []interface{} `json:"questions"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &detailResp)
+	assert.Equal(t, "upcoming", detailResp.Data.Status)
+	assert.Empty(t, detailResp.Data.Questions)
+
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"blank","answer":"x"}]}`
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTimedAttempt_AutoSubmitWhenTimeUp(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:              uuid.New().String(),
+		Title:           "Timed Experiment",
+		Deadline:        time.Now().Add(24 * time.Hour),
+		DurationMinutes: 30,
+		Users:           []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5},
+		},
+	}
+	db.Create(&exp)
+
+	// 首次打开详情开始计时
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	GetExperimentDetail_Student(c)
+	var detailResp struct {
+		Data struct {
+			SubmissionStatus string `json:"submission_status"`
+			EndsAt           string `json:"ends_at"`
+			RemainingSeconds int    `json:"remaining_seconds"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &detailResp)
+	assert.Equal(t, "in_progress", detailResp.Data.SubmissionStatus)
+	assert.NotEmpty(t, detailResp.Data.EndsAt)
+	assert.InDelta(t, 30*60, detailResp.Data.RemainingSeconds, 5)
+
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"blank","answer":"2"}]}`
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/save", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SaveAnswer(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 模拟限时已到
+	db.Model(&models.ExperimentSubmission{}).Where("experiment_id = ?", exp.ID).
+		Update("ends_at", time.Now().Add(-time.Minute))
+
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/save", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SaveAnswer(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var submission models.ExperimentSubmission
+	db.Where("experiment_id = ?", exp.ID).First(&submission)
+	assert.Equal(t, "submitted", submission.Status)
+	assert.Equal(t, 5, submission.TotalScore)
+}
+
+// 实验列表只标记作答时间已到，不评分；打开实验详情时才自动提交
+func TestTimedAttempt_ListDoesNotAutoSubmit(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:              uuid.New().String(),
+		Title:           "Timed Experiment",
+		Deadline:        time.Now().Add(24 * time.Hour),
+		DurationMinutes: 30,
+		Users:           []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5},
+		},
+	}
+	db.Create(&exp)
+	startedAt, endsAt := time.Now().Add(-time.Hour), time.Now().Add(-time.Minute)
+	submission := models.ExperimentSubmission{
+		ID: uuid.New().String(), ExperimentID: exp.ID, StudentID: user.ID, Status: "in_progress",
+		StartedAt: &startedAt, EndsAt: &endsAt, CreatedAt: startedAt,
+	}
+	db.Create(&submission)
+
+	c, w := setupTestContext(user)
+	c.Request, _ = http.NewRequest("GET", "/experiments?page=1&limit=10", nil)
+	GetExperiments_Student(c)
+	var listResp struct {
+		Data []struct {
+			SubmissionStatus string `json:"submission_status"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	if assert.Len(t, listResp.Data, 1) {
+		assert.Equal(t, "time_up", listResp.Data[0].SubmissionStatus)
+	}
+	db.First(&submission, "id = ?", submission.ID)
+	assert.Equal(t, "in_progress", submission.Status)
+
+	c, w = setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	GetExperimentDetail_Student(c)
+	assert.Contains(t, w.Body.String(), `"submission_status":"submitted"`)
+	db.First(&submission, "id = ?", submission.ID)
+	assert.Equal(t, "submitted", submission.Status)
+}
+
+// 自动提交失败回滚时不修改内存中的提交记录
+func TestAutoSubmitExpiredAttempt_RollbackKeepsSubmission(t *testing.T) {
+	db := setupTestDB()
+	startedAt, endsAt := time.Now().Add(-time.Hour), time.Now().Add(-time.Minute)
+	submission := models.ExperimentSubmission{
+		ID: uuid.New().String(), ExperimentID: "missing", StudentID: 1, Status: "in_progress",
+		StartedAt: &startedAt, EndsAt: &endsAt,
+	}
+	db.Create(&submission)
+
+	assert.Error(t, autoSubmitExpiredAttempt(db, &submission))
+	assert.Equal(t, "in_progress", submission.Status)
+	assert.Zero(t, submission.Attempt)
+}
+
+func TestEffectiveScore(t *testing.T) {
+	attempts := []models.ExperimentSubmission{{TotalScore: 6}, {TotalScore: 9}, {TotalScore: 4}}
+	assert.Equal(t, 9.0, effectiveScore("best", attempts))
+	assert.Equal(t, 4.0, effectiveScore("last", attempts))
+	assert.Equal(t, 6.33, effectiveScore("average", attempts))
+	assert.Equal(t, 0.0, effectiveScore("best", nil))
+}
+
+func TestSubmitExperiment_MaxAttempts(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:            uuid.New().String(),
+		Title:         "Two Attempts",
+		Permission:    1,
+		Deadline:      time.Now().Add(24 * time.Hour),
+		MaxAttempts:   2,
+		ScoringPolicy: "best",
+		Users:         []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5},
+		},
+	}
+	db.Create(&exp)
+
+	submit := func(answer string) *httptest.ResponseRecorder {
+		body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"blank","answer":"` + answer + `"}]}`
+		c, w := setupTestContext(user)
+		c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+		c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		SubmitExperiment(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, submit("2").Code)
+	assert.Equal(t, http.StatusOK, submit("3").Code)
+	assert.Equal(t, http.StatusBadRequest, submit("2").Code)
+
+	var count int64
+	db.Model(&models.ExperimentSubmission{}).Where("experiment_id = ?", exp.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	c, w := setupTestContext(user)
+	c.Request, _ = http.NewRequest("GET", "/submissions?experiment_id="+exp.ID, nil)
+	GetSubmissions(c)
+	var resp struct {
+		Data []struct {
+			AttemptNumber  int     `json:"attempt_number"`
+			TotalScore     int     `json:"total_score"`
+			EffectiveScore float64 `json:"effective_score"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 2)
+	for _, sub := range resp.Data {
+		assert.Equal(t, 5.0, sub.EffectiveScore)
+	}
+}
+
+func TestLatePenaltyPercent(t *testing.T) {
+	deadline := time.Now()
+	exp := models.Experiment{
+		Permission:         1,
+		Deadline:           deadline,
+		LatePenaltyPercent: 10,
+		LatePenaltyUnit:    "hour",
+		LateGraceMinutes:   15,
+		LateMaxPenalty:     30,
+	}
+	assert.Equal(t, 0.0, latePenaltyPercent(exp, deadline.Add(-time.Minute)))
+	assert.Equal(t, 0.0, latePenaltyPercent(exp, deadline.Add(10*time.Minute)))
+	assert.Equal(t, 10.0, latePenaltyPercent(exp, deadline.Add(30*time.Minute)))
+	assert.Equal(t, 20.0, latePenaltyPercent(exp, deadline.Add(90*time.Minute)))
+	assert.Equal(t, 30.0, latePenaltyPercent(exp, deadline.Add(10*time.Hour)))
+
+	total, penalty := applyLatePenalty(exp, 50, deadline.Add(90*time.Minute))
+	assert.Equal(t, 40, total)
+	assert.Equal(t, 10, penalty)
+
+	exp.LateCutoffHours = 2
+	assert.False(t, submissionClosed(exp, deadline.Add(time.Hour)))
+	assert.True(t, submissionClosed(exp, deadline.Add(3*time.Hour)))
+}
+
+func TestSubmitExperiment_LatePenalty(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:                 uuid.New().String(),
+		Title:              "Late Experiment",
+		Permission:         1,
+		Deadline:           time.Now().Add(-26 * time.Hour),
+		LatePenaltyPercent: 20,
+		LatePenaltyUnit:    "day",
+		Users:              []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 10},
+		},
+	}
+	db.Create(&exp)
+
+	body := `{"answers":[{"question_id":"` + exp.Questions[0].ID + `","type":"blank","answer":"2"}]}`
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data struct {
+			TotalScore  string `json:"total_score"`
+			RawScore    int    `json:"raw_score"`
+			LatePenalty int    `json:"late_penalty"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "6/10", resp.Data.TotalScore)
+	assert.Equal(t, 10, resp.Data.RawScore)
+	assert.Equal(t, 4, resp.Data.LatePenalty)
+}
+
+// 个人限时到时后实验又截止，保存和提交仍会先自动提交草稿，而不是以截止为由拒绝
+func TestTimedAttempt_AutoSubmitAfterDeadline(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{
+		ID:              uuid.New().String(),
+		Title:           "Timed Experiment",
+		Deadline:        time.Now().Add(-10 * time.Minute),
+		DurationMinutes: 30,
+		Users:           []models.User{user},
+		Questions: []models.Question{
+			{ID: uuid.New().String(), Type: "blank", Content: "1+1", CorrectAnswer: "2", Score: 5},
+		},
+	}
+	db.Create(&exp)
+	startedAt, endsAt := time.Now().Add(-time.Hour), time.Now().Add(-30*time.Minute)
+	submission := models.ExperimentSubmission{
+		ID: uuid.New().String(), ExperimentID: exp.ID, StudentID: user.ID, Status: "in_progress",
+		StartedAt: &startedAt, EndsAt: &endsAt, CreatedAt: startedAt,
+	}
+	db.Create(&submission)
+	db.Create(&models.QuestionSubmission{ID: uuid.New().String(), SubmissionID: submission.ID,
+		QuestionID: exp.Questions[0].ID, Type: "blank", Answer: "2"})
+
+	c, w := setupTestContext(user)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request, _ = http.NewRequest("POST", "/submit", bytes.NewBufferString(`{"answers":[]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "submitted automatically")
+
+	db.First(&submission, "id = ?", submission.ID)
+	assert.Equal(t, "submitted", submission.Status)
+	assert.Equal(t, 5, submission.TotalScore)
+}
+
+// 学生不再访问时，教师查看提交会自动提交到时的限时作答
+func TestFinalizeExpiredAttempts(t *testing.T) {
+	db := setupTestDB()
+	global.DB = db
+
+	user := setupTestUser(db)
+	exp := models.Experiment{ID: uuid.New().String(), Title: "Timed", Deadline: time.Now().Add(time.Hour), DurationMinutes: 30}
+	db.Create(&exp)
+	startedAt, endsAt, later := time.Now().Add(-time.Hour), time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	expired := models.ExperimentSubmission{ID: uuid.New().String(), ExperimentID: exp.ID, StudentID: user.ID,
+		Status: "in_progress", StartedAt: &startedAt, EndsAt: &endsAt}
+	running := models.ExperimentSubmission{ID: uuid.New().String(), ExperimentID: exp.ID, StudentID: user.ID + 1,
+		Status: "in_progress", StartedAt: &startedAt, EndsAt: &later}
+	db.Create(&expired)
+	db.Create(&running)
+
+	assert.NoError(t, finalizeExpiredAttempts(db, exp.ID))
+	db.First(&expired, "id = ?", expired.ID)
+	db.First(&running, "id = ?", running.ID)
+	assert.Equal(t, "submitted", expired.Status)
+	assert.Equal(t, endsAt.Unix(), expired.SubmittedAt.Unix())
+	assert.Equal(t, "in_progress", running.Status)
+}
+
+func TestAnswersRevealed(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		name       string
+		experiment models.Experiment
+		used       int
+		want       bool
+	}{
+		{"不允许迟交，截止前", models.Experiment{Permission: 0, Deadline: now.Add(time.Hour)}, 1, false},
+		{"不允许迟交，截止后", models.Experiment{Permission: 0, Deadline: past}, 0, true},
+		{"迟交硬性截止前", models.Experiment{Permission: 1, Deadline: past, LateCutoffHours: 2}, 1, false},
+		{"迟交硬性截止后", models.Experiment{Permission: 1, Deadline: now.Add(-3 * time.Hour), LateCutoffHours: 2}, 0, true},
+		{"一直接收迟交", models.Experiment{Permission: 1, Deadline: past}, 5, false},
+		{"一直接收迟交但次数已用完", models.Experiment{Permission: 1, Deadline: past, MaxAttempts: 2}, 2, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, answersRevealed(tt.experiment, tt.used, now), tt.name)
+	}
+}
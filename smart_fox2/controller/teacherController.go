@@ -147,6 +147,7 @@ func CreateStudentGroup(c *gin.Context) {
 	var req struct {
 		GroupName  string   `json:"group_name" binding:"required"`
 		StudentIDs []string `json:"student_ids" binding:"required"`
+		CourseID   string   `json:"course_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -187,9 +188,26 @@ func CreateStudentGroup(c *gin.Context) {
 		})
 		return
 	}
+	if req.CourseID != "" {
+		if !canManageCourse(db, req.CourseID, currentUser(c).ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "无权在该课程下创建分组",
+			})
+			return
+		}
+		if err := checkCourseStudents(db, req.CourseID, studentIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 	// 创建分组
 	newGroup := models.Group{
-		Name: req.GroupName,
+		Name:     req.GroupName,
+		CourseID: req.CourseID,
 	}
 
 	// 使用事务确保数据一致性
@@ -229,10 +247,12 @@ func CreateStudentGroup(c *gin.Context) {
 	var GroupResponse struct {
 		GroupID    string   `json:"group_id"`
 		GroupName  string   `json:"group_name"`
+		CourseID   string   `json:"course_id"`
 		StudentIDs []string `json:"student_ids"`
 	}
 	GroupResponse.GroupID = strconv.FormatUint(uint64(newGroup.ID), 10)
 	GroupResponse.GroupName = newGroup.Name
+	GroupResponse.CourseID = newGroup.CourseID
 	GroupResponse.StudentIDs = req.StudentIDs
 	c.JSON(http.StatusCreated, gin.H{
 		"code":    201,
@@ -248,14 +268,18 @@ func GetStudentGroup(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	db := global.DB
+	query := db.Model(&models.Group{})
+	if courseID := c.Query("course_id"); courseID != "" {
+		query = query.Where("course_id = ?", courseID)
+	}
 
 	// 查询分组总数
 	var total int64
-	db.Model(&models.Group{}).Count(&total)
+	query.Count(&total)
 
 	// 查询分组数据
 	var groups []models.Group
-	result := db.Offset(offset).Limit(limit).Find(&groups)
+	result := query.Offset(offset).Limit(limit).Find(&groups)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -294,6 +318,7 @@ func GetStudentGroup(c *gin.Context) {
 	type GroupResponse struct {
 		GroupID      string   `json:"group_id"`
 		GroupName    string   `json:"group_name"`
+		CourseID     string   `json:"course_id"`
 		StudentCount int      `json:"student_count"`
 		StudentIDs   []string `json:"student_ids"`
 	}
@@ -307,6 +332,7 @@ func GetStudentGroup(c *gin.Context) {
 		response[i] = GroupResponse{
 			GroupID:      strconv.FormatUint(uint64(group.ID), 10),
 			GroupName:    group.Name,
+			CourseID:     group.CourseID,
 			StudentCount: len(studentIDs),
 			StudentIDs:   studentIDs,
 		}
@@ -478,6 +504,13 @@ func DeleteStudentGroup(c *gin.Context) {
 		if err := tx.Model(&group).Association("Student").Clear(); err != nil {
 			return err
 		}
+		// 删除分组与实验、通知之间的关联关系
+		if err := tx.Table("experiment_groups").Where("group_id = ?", group.ID).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("notification_groups").Where("group_id = ?", group.ID).Delete(nil).Error; err != nil {
+			return err
+		}
 
 		// 删除分组（软删除）
 		if err := tx.Delete(&group).Error; err != nil {
@@ -502,34 +535,107 @@ func DeleteStudentGroup(c *gin.Context) {
 	})
 }
 
+// 辅助函数：解析并校验分组ID列表，指定课程时分组必须属于该课程
+func findGroups(db *gorm.DB, ids []string, courseID string) ([]models.Group, error) {
+	groupIDs := make([]uint, 0, len(ids))
+	for _, idStr := range ids {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的分组ID: %s", idStr)
+		}
+		groupIDs = append(groupIDs, uint(id))
+	}
+	var groups []models.Group
+	if len(groupIDs) == 0 {
+		return groups, nil
+	}
+	if err := db.Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) != len(groupIDs) {
+		return nil, errors.New("部分分组不存在")
+	}
+	for _, g := range groups {
+		if courseID != "" && g.CourseID != courseID {
+			return nil, fmt.Errorf("分组 %s 不属于该课程", g.Name)
+		}
+	}
+	return groups, nil
+}
+
 // TestCase 测试用例结构体
 type TestCase struct {
 	Input          interface{} `json:"input"`
 	ExpectedOutput interface{} `json:"expected_output"`
+	Weight         float64     `json:"weight,omitempty" binding:"gte=0"` // 用例权重，未设置时按 1 计
+	Group          string      `json:"group,omitempty"`                  // 所属子任务，同组用例全部通过才得分
+	Sample         bool        `json:"sample,omitempty"`                 // 公开样例，学生可以看到输入和期望输出，其余用例只显示是否通过
+}
+
+// LatePolicyInput 迟交策略输入，更新实验时整体替换原有策略
+type LatePolicyInput struct {
+	PenaltyPercent float64 `json:"penalty_percent" binding:"gte=0,lte=100"`
+	PenaltyUnit    string  `json:"penalty_unit" binding:"omitempty,oneof=hour day"`
+	GraceMinutes   int     `json:"grace_minutes" binding:"gte=0"`
+	CutoffHours    int     `json:"cutoff_hours" binding:"gte=0"`
+	MaxPenalty     float64 `json:"max_penalty" binding:"gte=0,lte=100"`
+}
+
+func (p LatePolicyInput) apply(experiment *models.Experiment) {
+	unit := p.PenaltyUnit
+	if unit == "" {
+		unit = "day"
+	}
+	experiment.LatePenaltyPercent = p.PenaltyPercent
+	experiment.LatePenaltyUnit = unit
+	experiment.LateGraceMinutes = p.GraceMinutes
+	experiment.LateCutoffHours = p.CutoffHours
+	experiment.LateMaxPenalty = p.MaxPenalty
+}
+
+// PoolInput 抽题规则输入结构体
+type PoolInput struct {
+	Pool       string `json:"pool" binding:"required,max=50"`
+	Difficulty string `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	DrawCount  int    `json:"draw_count" binding:"required,gt=0"`
+}
+
+func toQuestionPools(inputs []PoolInput, experimentID string) []models.QuestionPool {
+	pools := make([]models.QuestionPool, len(inputs))
+	for i, p := range inputs {
+		pools[i] = models.QuestionPool{
+			ExperimentID: experimentID,
+			Pool:         p.Pool,
+			Difficulty:   p.Difficulty,
+			DrawCount:    p.DrawCount,
+		}
+	}
+	return pools
 }
 
 // CreateExperiment 创建实验
 func CreateExperiment(c *gin.Context) {
 
-	// QuestionInput 题目输入结构体
-	type QuestionInput struct {
-		Type          string     `json:"type" binding:"required,oneof=choice blank code"`
-		Content       string     `json:"content" binding:"required"`
-		Options       []string   `json:"options" binding:"required_if=Type choice"`
-		CorrectAnswer string     `json:"correct_answer" binding:"required_if=Type choice required_if=Type blank"`
-		Score         int        `json:"score" binding:"required,gt=0"`
-		ImageURL      string     `json:"image_url" binding:"omitempty"`
-		Explanation   string     `json:"explanation" binding:"omitempty"`
-		TestCases     []TestCase `json:"test_cases" binding:"required_if=Type code"`
-	}
 	// CreateExperimentRequest 请求结构体
 	type CreateExperimentRequest struct {
-		Title       string          `json:"title" binding:"required"`
-		Description string          `json:"description"`
-		Permission  *int            `json:"permission" binding:"required,oneof=1 0"`
-		Deadline    time.Time       `json:"deadline" binding:"required"`
-		StudentIDs  []string        `json:"student_ids" binding:"required"`
-		Questions   []QuestionInput `json:"questions" binding:"required,dive"`
+		Title            string           `json:"title" binding:"required"`
+		Description      string           `json:"description"`
+		Permission       *int             `json:"permission" binding:"required,oneof=1 0"`
+		OpensAt          *time.Time       `json:"opens_at"`
+		Deadline         time.Time        `json:"deadline" binding:"required"`
+		Duration         int              `json:"duration_minutes" binding:"gte=0"`
+		MaxAttempts      int              `json:"max_attempts" binding:"gte=0"`
+		ShuffleQuestions bool             `json:"shuffle_questions"`
+		ShuffleOptions   bool             `json:"shuffle_options"`
+		Policy           string           `json:"scoring_policy" binding:"omitempty,oneof=best last average"`
+		LatePolicy       *LatePolicyInput `json:"late_policy"`
+		StudentIDs       []string         `json:"student_ids" binding:"required_without=GroupIDs"`
+		GroupIDs         []string         `json:"group_ids" binding:"required_without=StudentIDs"`
+		CoTeacherIDs     []string         `json:"co_teacher_ids"`
+		CourseID         string           `json:"course_id"`
+		Questions        []QuestionInput  `json:"questions" binding:"required_without=BankQuestionIDs,dive"`
+		BankQuestionIDs  []string         `json:"bank_question_ids" binding:"required_without=Questions"`
+		Pools            []PoolInput      `json:"pools" binding:"omitempty,dive"`
 	}
 	// ExperimentResponseData 响应数据
 	type ExperimentResponseData struct {
@@ -539,9 +645,10 @@ func CreateExperiment(c *gin.Context) {
 	}
 	// CreateExperimentResponse 响应结构体
 	type CreateExperimentResponse struct {
-		Status  string                 `json:"status"`
-		Data    ExperimentResponseData `json:"data,omitempty"`
-		Message string                 `json:"message,omitempty"`
+		Status   string                 `json:"status"`
+		Data     ExperimentResponseData `json:"data,omitempty"`
+		Message  string                 `json:"message,omitempty"`
+		Warnings []TestCaseWarning      `json:"warnings,omitempty"` // 参考答案未通过的测试用例
 	}
 
 	db := global.DB
@@ -561,6 +668,29 @@ func CreateExperiment(c *gin.Context) {
 		})
 		return
 	}
+	if req.OpensAt != nil && !req.OpensAt.Before(req.Deadline) {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: "开放时间必须早于截止日期",
+		})
+		return
+	}
+	teacher := currentUser(c)
+	coTeachers, err := findCoTeachers(db, req.CoTeacherIDs, teacher.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.CourseID != "" && !canManageCourse(db, req.CourseID, teacher.ID) {
+		c.JSON(http.StatusForbidden, CreateExperimentResponse{
+			Status:  "error",
+			Message: "无权在该课程下创建实验",
+		})
+		return
+	}
 	experimentID := uuid.New().String()
 	// 处理附件上传
 	form, err := c.MultipartForm()
@@ -600,56 +730,81 @@ func CreateExperiment(c *gin.Context) {
 
 	// 创建实验
 	experiment := models.Experiment{
-		ID:          experimentID,
-		Title:       req.Title,
-		Permission:  *req.Permission,
-		Description: req.Description,
-		Deadline:    req.Deadline,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		Attachments: attachments,
+		ID:               experimentID,
+		Title:            req.Title,
+		Permission:       *req.Permission,
+		CourseID:         req.CourseID,
+		Description:      req.Description,
+		OpensAt:          req.OpensAt,
+		Deadline:         req.Deadline,
+		DurationMinutes:  req.Duration,
+		MaxAttempts:      req.MaxAttempts,
+		ShuffleQuestions: req.ShuffleQuestions,
+		ShuffleOptions:   req.ShuffleOptions,
+		ScoringPolicy:    req.Policy,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		Attachments:      attachments,
+		TeacherID:        teacher.ID,
+		CoTeachers:       coTeachers,
+	}
+	if req.LatePolicy != nil {
+		req.LatePolicy.apply(&experiment)
 	}
 	// 处理题目
+	var warnings []TestCaseWarning
 	for _, q := range req.Questions {
-		question := models.Question{
-			ID:           uuid.NewString(),
-			ExperimentID: experimentID,
-			Type:         q.Type,
-			Content:      q.Content,
-			Score:        q.Score,
-			ImageURL:     q.ImageURL,
-			Explanation:  q.Explanation,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		}
-		if q.Type == "choice" {
-			// 将选项序列化为 JSON 字符串
-			optionsJSON, _ := json.Marshal(q.Options)
-			question.Options = string(optionsJSON)
-			question.CorrectAnswer = q.CorrectAnswer
-		}
-		if q.Type == "blank" {
-			question.CorrectAnswer = q.CorrectAnswer
-		}
-		if q.Type == "code" && len(q.TestCases) > 0 {
-			testCasesJSON, _ := json.Marshal(q.TestCases)
-			question.TestCases = string(testCasesJSON)
-		}
-		experiment.Questions = append(experiment.Questions, question)
-	}
-	var users []models.User
-	for _, studentID := range req.StudentIDs {
-		var user models.User
-		if err := db.First(&user, "id = ?", common.StrToUint(studentID)).Error; err != nil {
+		question, warning, err := buildQuestion(uuid.NewString(), experimentID, q)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, CreateExperimentResponse{
 				Status:  "error",
-				Message: fmt.Sprintf("找不到学生ID: %s", studentID),
+				Message: err.Error(),
 			})
 			return
 		}
-		users = append(users, user)
+		if warning != nil {
+			warnings = append(warnings, *warning)
+		}
+		question.CreatedAt = time.Now()
+		question.UpdatedAt = time.Now()
+		experiment.Questions = append(experiment.Questions, question)
+	}
+	// 从题库复制题目，之后修改题库不影响本实验
+	bankQuestions, err := copyBankQuestions(db, req.BankQuestionIDs, teacher.ID, experimentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	experiment.Questions = append(experiment.Questions, bankQuestions...)
+	experiment.Pools = toQuestionPools(req.Pools, experimentID)
+	if err := validatePools(experiment.Questions, experiment.Pools); err != nil {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	users, err := findAssignedStudents(db, req.StudentIDs, req.CourseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	groups, err := findGroups(db, req.GroupIDs, req.CourseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, CreateExperimentResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
 	}
 	experiment.Users = users
+	experiment.Groups = groups
 	// 保存到数据库
 	if err := db.Create(&experiment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, CreateExperimentResponse{
@@ -660,30 +815,92 @@ func CreateExperiment(c *gin.Context) {
 	}
 
 	//下发通知
+	notifyNewExperiment(db, experiment, teacher.ID)
+
+	// 返回成功响应
+	c.JSON(http.StatusCreated, CreateExperimentResponse{
+		Status:   "success",
+		Warnings: warnings,
+		Data: ExperimentResponseData{
+			ExperimentID: experiment.ID,
+			Title:        experiment.Title,
+			CreatedAt:    experiment.CreatedAt,
+		},
+	})
+
+}
+
+// findAssignedStudents 按 ID 查找实验分配的学生，指定课程时学生必须选修该课程
+func findAssignedStudents(db *gorm.DB, studentIDs []string, courseID string) ([]models.User, error) {
+	var users []models.User
+	for _, studentID := range studentIDs {
+		var user models.User
+		if err := db.First(&user, "id = ?", common.StrToUint(studentID)).Error; err != nil {
+			return nil, fmt.Errorf("找不到学生ID: %s", studentID)
+		}
+		users = append(users, user)
+	}
+	if courseID != "" {
+		userIDs := make([]uint, len(users))
+		for i, u := range users {
+			userIDs[i] = u.ID
+		}
+		if err := checkCourseStudents(db, courseID, userIDs); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// checkAssignmentsInCourse 实验移入其他课程时，已分配的学生必须选修新课程；未同时替换分组时，已分配的分组也必须属于新课程
+func checkAssignmentsInCourse(db *gorm.DB, experiment models.Experiment, courseID string, checkGroups bool) error {
+	var users []models.User
+	if err := db.Model(&experiment).Association("Users").Find(&users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if !isEnrolledInCourse(db, courseID, u.ID) {
+			return fmt.Errorf("学生 %s 未选修该课程", u.Name)
+		}
+	}
+	if !checkGroups {
+		return nil
+	}
+	var groups []models.Group
+	if err := db.Model(&experiment).Association("Groups").Find(&groups); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.CourseID != courseID {
+			return fmt.Errorf("分组 %s 不属于该课程", g.Name)
+		}
+	}
+	return nil
+}
+
+// notifyNewExperiment 向实验分配的学生和分组下发新实验通知
+func notifyNewExperiment(db *gorm.DB, experiment models.Experiment, teacherID uint) {
+	content := fmt.Sprintf("您有一个新的实验《%s》，请在 %s 前完成提交。", experiment.Title, experiment.Deadline.Format("2006-01-02 15:04"))
+	if experiment.OpensAt != nil && experiment.OpensAt.After(time.Now()) {
+		content = fmt.Sprintf("您有一个新的实验《%s》，将于 %s 开放，请在 %s 前完成提交。", experiment.Title,
+			experiment.OpensAt.Format("2006-01-02 15:04"), experiment.Deadline.Format("2006-01-02 15:04"))
+	}
 	notification := models.Notification{
 		ID:           uuid.New().String(),
 		Title:        fmt.Sprintf("新实验发布：%s", experiment.Title),
-		Content:      fmt.Sprintf("您有一个新的实验《%s》，请在 %s 前完成提交。", experiment.Title, experiment.Deadline.Format("2006-01-02 15:04")),
+		Content:      content,
 		ExperimentID: experiment.ID,
+		CourseID:     experiment.CourseID,
 		IsImportant:  false,
+		TeacherID:    teacherID,
 		CreatedAt:    time.Now(),
-		Users:        users, // 直接复用前面查到的学生
+		Users:        experiment.Users, // 直接复用前面查到的学生
+		Groups:       experiment.Groups,
 	}
 
 	if err := db.Create(&notification).Error; err != nil {
 		fmt.Printf("创建通知失败: %v\n", err)
 	}
-
-	// 返回成功响应
-	c.JSON(http.StatusCreated, CreateExperimentResponse{
-		Status: "success",
-		Data: ExperimentResponseData{
-			ExperimentID: experiment.ID,
-			Title:        experiment.Title,
-			CreatedAt:    experiment.CreatedAt,
-		},
-	})
-
 }
 
 // 获取实验列表
@@ -696,16 +913,13 @@ func GetExperiments_Teacher(c *gin.Context) {
 	status := c.DefaultQuery("status", "all")
 
 	offset := (page - 1) * limit
-	query := db.Model(&models.Experiment{})
+	query := manageableExperiments(db.Model(&models.Experiment{}), currentUser(c))
+	if courseID := c.Query("course_id"); courseID != "" {
+		query = query.Where("course_id = ?", courseID)
+	}
 
 	// 状态筛选逻辑
-	now := time.Now()
-	switch status {
-	case "active":
-		query = query.Where("deadline > ?", now)
-	case "expired":
-		query = query.Where("deadline <= ?", now)
-	}
+	query = filterExperimentStatus(query, status, time.Now())
 
 	var experiments []models.Experiment
 	var total int64
@@ -729,9 +943,11 @@ func GetExperiments_Teacher(c *gin.Context) {
 		response[i] = gin.H{
 			"experiment_id": exp.ID,
 			"title":         exp.Title,
+			"course_id":     exp.CourseID,
+			"opens_at":      formatTimePtr(exp.OpensAt),
 			"deadline":      exp.Deadline.Format(time.RFC3339),
 			"created_at":    exp.CreatedAt.Format(time.RFC3339),
-			"status":        getExperimentStatus(exp.Deadline),
+			"status":        getExperimentStatus(exp.OpensAt, exp.Deadline),
 		}
 	}
 
@@ -747,13 +963,123 @@ func GetExperiments_Teacher(c *gin.Context) {
 }
 
 // 辅助函数：获取实验状态
-func getExperimentStatus(deadline time.Time) string {
-	if time.Now().Before(deadline) {
+func getExperimentStatus(opensAt *time.Time, deadline time.Time) string {
+	now := time.Now()
+	if opensAt != nil && now.Before(*opensAt) {
+		return "upcoming"
+	}
+	if now.Before(deadline) {
 		return "active"
 	}
 	return "expired"
 }
 
+// 辅助函数：按实验状态（upcoming / active / expired）筛选
+func filterExperimentStatus(query *gorm.DB, status string, now time.Time) *gorm.DB {
+	switch status {
+	case "upcoming":
+		query = query.Where("opens_at > ?", now)
+	case "active":
+		query = query.Where("(opens_at IS NULL OR opens_at <= ?) AND deadline > ?", now, now)
+	case "expired":
+		query = query.Where("deadline <= ?", now)
+	}
+	return query
+}
+
+// 辅助函数：格式化可为空的时间
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// 辅助函数：获取当前登录用户
+func currentUser(c *gin.Context) models.User {
+	user, _ := c.Get("user")
+	u, _ := user.(models.User)
+	return u
+}
+
+// 辅助函数：判断用户是否为管理员，管理员角色只能在数据库中直接设置
+func isAdmin(user models.User) bool {
+	return user.Role == "admin"
+}
+
+// 辅助函数：判断教师是否为实验创建者或协作教师
+// 没有归属教师的历史实验（teacher_id = 0）只允许管理员管理
+func canManageExperiment(db *gorm.DB, experiment models.Experiment, user models.User) bool {
+	if experiment.TeacherID == 0 {
+		return isAdmin(user)
+	}
+	if experiment.TeacherID == user.ID {
+		return true
+	}
+	var count int64
+	db.Table("experiment_teachers").
+		Where("experiment_id = ? AND user_id = ?", experiment.ID, user.ID).
+		Count(&count)
+	return count > 0
+}
+
+// 辅助函数：限定查询范围为教师可管理的实验
+func manageableExperiments(db *gorm.DB, user models.User) *gorm.DB {
+	coTeaching := db.Session(&gorm.Session{NewDB: true}).Table("experiment_teachers").
+		Select("experiment_id").
+		Where("user_id = ?", user.ID)
+	owners := []uint{user.ID}
+	if isAdmin(user) {
+		owners = append(owners, 0)
+	}
+	return db.Where("experiments.teacher_id IN ? OR experiments.id IN (?)", owners, coTeaching)
+}
+
+// BackfillExperimentOwners 为没有归属教师的历史实验补全创建者：
+// 所属课程只有一位教师时归该教师，否则归最早为该实验发布通知的教师；仍无法确定的实验只允许管理员管理
+func BackfillExperimentOwners(db *gorm.DB) error {
+	var experiments []models.Experiment
+	if err := db.Where("teacher_id = ?", 0).Find(&experiments).Error; err != nil {
+		return err
+	}
+	for _, experiment := range experiments {
+		var owners []uint
+		if experiment.CourseID != "" {
+			db.Table("course_teachers").Where("course_id = ?", experiment.CourseID).Pluck("user_id", &owners)
+		}
+		if len(owners) != 1 {
+			owners = nil
+			db.Model(&models.Notification{}).
+				Where("experiment_id = ? AND teacher_id <> ?", experiment.ID, 0).
+				Order("created_at ASC").Limit(1).
+				Pluck("teacher_id", &owners)
+		}
+		if len(owners) != 1 {
+			continue
+		}
+		if err := db.Model(&models.Experiment{}).Where("id = ? AND teacher_id = ?", experiment.ID, 0).
+			Update("teacher_id", owners[0]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 辅助函数：解析并校验协作教师ID列表，忽略实验创建者本人
+func findCoTeachers(db *gorm.DB, ids []string, ownerID uint) ([]models.User, error) {
+	teachers, err := findUsersByRole(db, ids, "teacher")
+	if err != nil {
+		return nil, err
+	}
+	coTeachers := make([]models.User, 0, len(teachers))
+	for _, t := range teachers {
+		if t.ID != ownerID {
+			coTeachers = append(coTeachers, t)
+		}
+	}
+	return coTeachers, nil
+}
+
 // 获取实验详情
 func GetExperimentDetail_Teacher(c *gin.Context) {
 	db := common.GetDB()
@@ -762,6 +1088,9 @@ func GetExperimentDetail_Teacher(c *gin.Context) {
 	var experiment models.Experiment
 	result := db.Preload("Questions").
 		Preload("Users").
+		Preload("CoTeachers").
+		Preload("Groups").
+		Preload("Pools").
 		Where("ID = ?", experimentID).
 		First(&experiment)
 
@@ -779,12 +1108,27 @@ func GetExperimentDetail_Teacher(c *gin.Context) {
 		}
 		return
 	}
+	if !canManageExperiment(db, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "无权访问该实验",
+		})
+		return
+	}
 	// 新增：提取学生ID列表
 	studentIDs := make([]string, len(experiment.Users))
 	for i, user := range experiment.Users {
 
 		studentIDs[i] = strconv.FormatUint(uint64(user.ID), 10)
 	}
+	coTeacherIDs := make([]string, len(experiment.CoTeachers))
+	for i, teacher := range experiment.CoTeachers {
+		coTeacherIDs[i] = strconv.FormatUint(uint64(teacher.ID), 10)
+	}
+	groupIDs := make([]string, len(experiment.Groups))
+	for i, group := range experiment.Groups {
+		groupIDs[i] = strconv.FormatUint(uint64(group.ID), 10)
+	}
 	// 处理题目数据
 	questions := make([]gin.H, len(experiment.Questions))
 	for i, q := range experiment.Questions {
@@ -795,6 +1139,8 @@ func GetExperimentDetail_Teacher(c *gin.Context) {
 			"score":       q.Score,
 			"image_url":   q.ImageURL,
 			"explanation": q.Explanation,
+			"pool":        q.Pool,
+			"difficulty":  q.Difficulty,
 		}
 
 		// 处理不同类型题目特有字段
@@ -804,11 +1150,37 @@ func GetExperimentDetail_Teacher(c *gin.Context) {
 			fallthrough
 		case "blank":
 			questionData["correct_answer"] = q.CorrectAnswer
+		case "multi_choice":
+			questionData["options"] = common.ParseJSONArray(q.Options)
+			questionData["correct_answers"] = common.ParseJSONArray(q.CorrectAnswer)
+			questionData["scoring_mode"] = multiChoiceScoringMode(q)
+		case "numeric":
+			questionData["correct_answer"] = q.CorrectAnswer
+			questionData["tolerance"] = q.Tolerance
+			questionData["tolerance_type"] = numericToleranceType(q)
+			questionData["unit"] = q.Unit
+		case "true_false":
+			questionData["correct_answer"] = q.CorrectAnswer
 		case "code":
 			var testCases []TestCase
 			if err := json.Unmarshal([]byte(q.TestCases), &testCases); err == nil {
 				questionData["test_cases"] = testCases
 			}
+			questionData["time_limit"] = q.TimeLimit
+			questionData["memory_limit_mb"] = q.MemoryLimitMB
+			questionData["language_limits"] = parseLanguageLimits(q)
+			questionData["compare_mode"] = compareMode(q)
+			questionData["float_epsilon"] = q.FloatEpsilon
+			questionData["checker"] = parseChecker(q)
+			questionData["starter_code"] = parseLanguageCode(q.StarterCode)
+			questionData["harness"] = parseLanguageCode(q.Harness)
+			questionData["reference_solution"] = parseReferenceSolution(q)
+		}
+		if config, ok := parseBlankConfig(q); ok && q.Type == "blank" {
+			questionData["blank_config"] = config
+		}
+		if rubric := parseRubric(q); rubric != nil {
+			questionData["rubric"] = rubric
 		}
 
 		questions[i] = questionData
@@ -817,14 +1189,26 @@ func GetExperimentDetail_Teacher(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data": gin.H{
-			"experiment_id": experiment.ID,
-			"title":         experiment.Title,
-			"description":   experiment.Description,
-			"permission":    experiment.Permission,
-			"student_ids":   studentIDs,
-			"deadline":      experiment.Deadline.Format(time.RFC3339),
-			"questions":     questions,
-			"created_at":    experiment.CreatedAt.Format(time.RFC3339),
+			"experiment_id":     experiment.ID,
+			"title":             experiment.Title,
+			"description":       experiment.Description,
+			"permission":        experiment.Permission,
+			"course_id":         experiment.CourseID,
+			"teacher_id":        strconv.FormatUint(uint64(experiment.TeacherID), 10),
+			"co_teacher_ids":    coTeacherIDs,
+			"student_ids":       studentIDs,
+			"group_ids":         groupIDs,
+			"opens_at":          formatTimePtr(experiment.OpensAt),
+			"deadline":          experiment.Deadline.Format(time.RFC3339),
+			"duration_minutes":  experiment.DurationMinutes,
+			"max_attempts":      experiment.MaxAttempts,
+			"shuffle_questions": experiment.ShuffleQuestions,
+			"shuffle_options":   experiment.ShuffleOptions,
+			"scoring_policy":    scoringPolicy(experiment),
+			"late_policy":       latePolicyResponse(experiment),
+			"pools":             experiment.Pools,
+			"questions":         questions,
+			"created_at":        experiment.CreatedAt.Format(time.RFC3339),
 		},
 	})
 }
@@ -852,6 +1236,17 @@ func GetStudentSubmissions(c *gin.Context) {
 		}
 		return
 	}
+	if !canManageExperiment(db, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "无权访问该实验",
+		})
+		return
+	}
+	// 学生不再访问时，到时的限时作答在这里自动提交
+	if err := finalizeExpiredAttempts(db, experiment.ID); err != nil {
+		log.Printf("自动提交到时作答失败: %v", err)
+	}
 	var student models.User
 	result = db.Where("ID = ?", studentID).
 		First(&student)
@@ -871,24 +1266,46 @@ func GetStudentSubmissions(c *gin.Context) {
 	}
 	// 准备响应数据结构
 	type QuestionResult struct {
-		QuestionID      string   `json:"question_id"`
-		Type            string   `json:"type"`
-		Content         string   `json:"content"`
-		Options         []string `json:"options,omitempty"`
-		Score           int      `json:"score"`
-		StudentAnswer   string   `json:"student_answer,omitempty"`
-		StudentCode     string   `json:"student_code,omitempty"`
-		StudentLanguage string   `json:"student_language,omitempty"`
-		Feedback        string   `json:"feedback,omitempty"`
+		QuestionID      string        `json:"question_id"`
+		Type            string        `json:"type"`
+		Content         string        `json:"content"`
+		Options         []string      `json:"options,omitempty"`
+		Score           int           `json:"score"`
+		StudentAnswer   string        `json:"student_answer,omitempty"`
+		StudentCode     string        `json:"student_code,omitempty"`
+		StudentLanguage string        `json:"student_language,omitempty"`
+		Feedback        string        `json:"feedback,omitempty"`
+		AnswerID        string        `json:"answer_id"`
+		ReviewStatus    string        `json:"review_status,omitempty"`
+		AutoScore       *int          `json:"auto_score,omitempty"`
+		OverrideReason  string        `json:"override_reason,omitempty"`
+		Comment         string        `json:"comment,omitempty"`
+		RubricGrades    []RubricGrade `json:"rubric_grades,omitempty"`
+		JudgeResult     *JudgeResult  `json:"judge_result,omitempty"`
+	}
+	type AttemptSummary struct {
+		SubmissionID  string    `json:"submission_id"`
+		AttemptNumber int       `json:"attempt_number"`
+		LatePenalty   int       `json:"late_penalty"`
+		TotalScore    int       `json:"total_score"`
+		SubmittedAt   time.Time `json:"submitted_at"`
 	}
 	var StudentSubmission struct {
-		StudentID    string           `json:"student_id"`
-		StudentName  string           `json:"student_name"`
-		Status       string           `json:"status"`
-		SubmissionID string           `json:"submission_id"`
-		TotalScore   int              `json:"total_score"`
-		SubmittedAt  time.Time        `json:"submitted_at"`
-		Results      []QuestionResult `json:"results"`
+		StudentID      string           `json:"student_id"`
+		StudentName    string           `json:"student_name"`
+		Status         string           `json:"status"`
+		SubmissionID   string           `json:"submission_id"`
+		AttemptNumber  int              `json:"attempt_number"`
+		RawScore       int              `json:"raw_score"`
+		LatePenalty    int              `json:"late_penalty"`
+		TotalScore     int              `json:"total_score"`
+		EffectiveScore float64          `json:"effective_score"`
+		ScoringPolicy  string           `json:"scoring_policy"`
+		MaxAttempts    int              `json:"max_attempts"`
+		Attempts       []AttemptSummary `json:"attempts"`
+		DrawnQuestions []string         `json:"drawn_question_ids,omitempty"`
+		SubmittedAt    time.Time        `json:"submitted_at"`
+		Results        []QuestionResult `json:"results"`
 	}
 	StudentSubmission.StudentID = strconv.FormatUint(uint64(studentID), 10)
 	StudentSubmission.StudentName = student.Name
@@ -899,13 +1316,13 @@ func GetStudentSubmissions(c *gin.Context) {
 		First(&latestSubmission).Error; err != nil {
 		// 学生没有提交记录，未开始
 		StudentSubmission.Status = "not_started"
-	} else if err := db.Where("experiment_id = ? AND student_id = ? AND status = ?", experimentID, studentID, "submitted").
+	} else if err := db.Where("experiment_id = ? AND student_id = ? AND status IN ?", experimentID, studentID, finishedStatuses).
 		Order("submitted_at DESC").
 		First(&latestSubmission).Error; err != nil {
 		StudentSubmission.Status = "in_progress"
 	} else {
 		// 获取该次提交的所有题目提交
-		StudentSubmission.Status = "submitted"
+		StudentSubmission.Status = latestSubmission.Status
 		var questionSubmissions []models.QuestionSubmission
 		if err := db.Preload("Question").
 			Where("submission_id = ?", latestSubmission.ID).
@@ -930,25 +1347,32 @@ func GetStudentSubmissions(c *gin.Context) {
 
 			// 解析选择题选项
 			var options []string
-			if question.Type == "choice" && question.Options != "" {
+			if (question.Type == "choice" || question.Type == "multi_choice") && question.Options != "" {
 				if err := json.Unmarshal([]byte(question.Options), &options); err != nil {
 					options = []string{}
 				}
 			}
 
 			result := QuestionResult{
-				QuestionID: question.ID,
-				Type:       question.Type,
-				Content:    question.Content,
-				Score:      qs.Score,
-				Feedback:   qs.Feedback,
+				QuestionID:     question.ID,
+				Type:           question.Type,
+				Content:        question.Content,
+				Score:          qs.Score,
+				Feedback:       qs.Feedback,
+				AnswerID:       qs.ID,
+				ReviewStatus:   qs.ReviewStatus,
+				AutoScore:      qs.AutoScore,
+				OverrideReason: qs.OverrideReason,
+				Comment:        qs.Comment,
+				RubricGrades:   parseRubricGrades(qs),
+				JudgeResult:    parseJudgeResult(qs),
 			}
 
 			// 根据题目类型设置不同字段
-			if question.Type == "choice" {
+			if question.Type == "choice" || question.Type == "multi_choice" {
 				result.Options = options
 				result.StudentAnswer = qs.Answer
-			} else if question.Type == "blank" {
+			} else if question.Type == "blank" || question.Type == "numeric" || question.Type == "true_false" || question.Type == "essay" {
 				result.StudentAnswer = qs.Answer
 			} else if question.Type == "code" {
 				result.StudentCode = qs.Code
@@ -958,10 +1382,41 @@ func GetStudentSubmissions(c *gin.Context) {
 			results = append(results, result)
 		}
 		StudentSubmission.SubmissionID = latestSubmission.ID
+		StudentSubmission.AttemptNumber = latestSubmission.Attempt
+		StudentSubmission.RawScore = latestSubmission.RawScore
+		StudentSubmission.LatePenalty = latestSubmission.LatePenalty
 		StudentSubmission.TotalScore = latestSubmission.TotalScore
 		StudentSubmission.SubmittedAt = latestSubmission.SubmittedAt
 
 	}
+	// 提交历史与按计分策略得出的有效成绩
+	attempts, err := submittedAttempts(db, experimentID, studentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "获取提交历史失败",
+		})
+		return
+	}
+	StudentSubmission.ScoringPolicy = scoringPolicy(experiment)
+	// 抽题实验中该学生抽到的题目
+	var drawnSubmission models.ExperimentSubmission
+	if err := db.Where("experiment_id = ? AND student_id = ? AND drawn_questions <> ''", experimentID, studentID).
+		First(&drawnSubmission).Error; err == nil {
+		StudentSubmission.DrawnQuestions = common.ParseJSONArray(drawnSubmission.DrawnQuestions)
+	}
+	StudentSubmission.MaxAttempts = experiment.MaxAttempts
+	StudentSubmission.EffectiveScore = effectiveScore(StudentSubmission.ScoringPolicy, attempts)
+	StudentSubmission.Attempts = make([]AttemptSummary, len(attempts))
+	for i, a := range attempts {
+		StudentSubmission.Attempts[i] = AttemptSummary{
+			SubmissionID:  a.ID,
+			AttemptNumber: a.Attempt,
+			LatePenalty:   a.LatePenalty,
+			TotalScore:    a.TotalScore,
+			SubmittedAt:   a.SubmittedAt,
+		}
+	}
 	if len(results) == 0 {
 		var nullResult QuestionResult
 		results = append(results, nullResult)
@@ -976,30 +1431,63 @@ func GetStudentSubmissions(c *gin.Context) {
 // UpdateExperiment 更新实验
 func UpdateExperiment(c *gin.Context) {
 	type UpdateQuestionInput struct {
-		QuestionID    string     `json:"question_id" binding:"omitempty,required_if=Type ''"`
-		Type          string     `json:"type" binding:"omitempty,oneof=choice blank code"`
-		Content       string     `json:"content" binding:"omitempty,min=1"`
-		Options       []string   `json:"options" binding:"omitempty,required_if=Type choice"`
-		CorrectAnswer string     `json:"correct_answer" binding:"omitempty,required_if=Type choice required_if=Type blank"`
-		Score         int        `json:"score" binding:"omitempty,gt=0"`
-		ImageURL      string     `json:"image_url" binding:"omitempty"`
-		Explanation   string     `json:"explanation" binding:"omitempty"`
-		TestCases     []TestCase `json:"test_cases" binding:"omitempty,required_if=Type code"`
+		QuestionID        string                   `json:"question_id" binding:"omitempty,required_if=Type ''"`
+		Type              string                   `json:"type" binding:"omitempty,oneof=choice multi_choice blank numeric true_false essay code"`
+		Content           string                   `json:"content" binding:"omitempty,min=1"`
+		Options           []string                 `json:"options" binding:"omitempty,required_if=Type choice"`
+		CorrectAnswer     string                   `json:"correct_answer" binding:"omitempty,required_if=Type choice required_if=Type blank"`
+		CorrectAnswers    []string                 `json:"correct_answers" binding:"omitempty"`
+		BlankConfig       *BlankConfig             `json:"blank_config"`
+		Rubric            []RubricCriterion        `json:"rubric"`
+		ScoringMode       string                   `json:"scoring_mode" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
+		Tolerance         *float64                 `json:"tolerance" binding:"omitempty,gte=0"`
+		ToleranceType     string                   `json:"tolerance_type" binding:"omitempty,oneof=absolute relative"`
+		Unit              *string                  `json:"unit" binding:"omitempty,max=20"`
+		Score             int                      `json:"score" binding:"omitempty,gt=0"`
+		ImageURL          string                   `json:"image_url" binding:"omitempty"`
+		Explanation       string                   `json:"explanation" binding:"omitempty"`
+		TestCases         []TestCase               `json:"test_cases" binding:"omitempty,required_if=Type code,dive"`
+		TimeLimit         *int                     `json:"time_limit" binding:"omitempty,gte=0,lte=60"`
+		MemoryLimitMB     *int                     `json:"memory_limit_mb" binding:"omitempty,gte=0,lte=4096"`
+		LanguageLimits    map[string]ResourceLimit `json:"language_limits" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"` // 传空对象表示清除
+		CompareMode       string                   `json:"compare_mode" binding:"omitempty,oneof=exact ignore_whitespace float checker"`
+		FloatEpsilon      *float64                 `json:"float_epsilon" binding:"omitempty,gte=0"`
+		Checker           *CheckerProgram          `json:"checker"`
+		StarterCode       map[string]string        `json:"starter_code" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"` // 传空对象表示清除
+		Harness           map[string]string        `json:"harness" binding:"omitempty,dive,keys,oneof=cpp java python,endkeys"`      // 传空对象表示清除
+		ReferenceSolution *ReferenceSolution       `json:"reference_solution"`
+		ValidateTestCases string                   `json:"validate_test_cases" binding:"omitempty,oneof=reject flag"` // 用参考答案校验测试用例
+		Pool              *string                  `json:"pool" binding:"omitempty,max=50"`
+		Difficulty        string                   `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
 	}
 	type UpdateExperimentRequest struct {
-		Title           string                `json:"title" binding:"omitempty,min=1"`
-		Description     string                `json:"description" binding:"omitempty"`
-		Deadline        time.Time             `json:"deadline" binding:"omitempty"`
-		Questions       []UpdateQuestionInput `json:"questions" binding:"omitempty,dive"`
-		RemoveQuestions []string              `json:"remove_questions" binding:"omitempty"`
-		Permission      *int                  `json:"permission" binding:"omitempty,oneof=0 1"`
+		Title            string                `json:"title" binding:"omitempty,min=1"`
+		Description      string                `json:"description" binding:"omitempty"`
+		OpensAt          *time.Time            `json:"opens_at" binding:"omitempty"`
+		ClearOpensAt     bool                  `json:"clear_opens_at"` // 为 true 时取消开放时间，实验立即开放
+		Duration         *int                  `json:"duration_minutes" binding:"omitempty,gte=0"`
+		MaxAttempts      *int                  `json:"max_attempts" binding:"omitempty,gte=0"`
+		ShuffleQuestions *bool                 `json:"shuffle_questions"`
+		ShuffleOptions   *bool                 `json:"shuffle_options"`
+		Policy           string                `json:"scoring_policy" binding:"omitempty,oneof=best last average"`
+		LatePolicy       *LatePolicyInput      `json:"late_policy" binding:"omitempty"`
+		Deadline         time.Time             `json:"deadline" binding:"omitempty"`
+		Questions        []UpdateQuestionInput `json:"questions" binding:"omitempty,dive"`
+		BankQuestionIDs  []string              `json:"bank_question_ids" binding:"omitempty"`
+		Pools            []PoolInput           `json:"pools" binding:"omitempty,dive"`
+		RemoveQuestions  []string              `json:"remove_questions" binding:"omitempty"`
+		Permission       *int                  `json:"permission" binding:"omitempty,oneof=0 1"`
+		CoTeacherIDs     []string              `json:"co_teacher_ids" binding:"omitempty"`
+		CourseID         *string               `json:"course_id" binding:"omitempty"`
+		GroupIDs         []string              `json:"group_ids" binding:"omitempty"`
 	}
 	type UpdateExperimentResponse struct {
-		Status       string    `json:"status"`
-		ExperimentID string    `json:"experiment_id,omitempty"`
-		Title        string    `json:"title,omitempty"`
-		UpdatedAt    time.Time `json:"updated_at,omitempty"`
-		Message      string    `json:"message,omitempty"`
+		Status       string            `json:"status"`
+		ExperimentID string            `json:"experiment_id,omitempty"`
+		Title        string            `json:"title,omitempty"`
+		UpdatedAt    time.Time         `json:"updated_at,omitempty"`
+		Message      string            `json:"message,omitempty"`
+		Warnings     []TestCaseWarning `json:"warnings,omitempty"` // 参考答案未通过的测试用例
 	}
 
 	db := global.DB
@@ -1022,6 +1510,14 @@ func UpdateExperiment(c *gin.Context) {
 		}
 		return
 	}
+	teacherID := currentUser(c).ID
+	if !canManageExperiment(db, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, UpdateExperimentResponse{
+			Status:  "error",
+			Message: "No permission to modify this experiment",
+		})
+		return
+	}
 
 	var req UpdateExperimentRequest
 	if err := c.ShouldBind(&req); err != nil {
@@ -1031,7 +1527,145 @@ func UpdateExperiment(c *gin.Context) {
 		})
 		return
 	}
+	if req.CourseID != nil && *req.CourseID != "" && !canManageCourse(db, *req.CourseID, teacherID) {
+		c.JSON(http.StatusForbidden, UpdateExperimentResponse{
+			Status:  "error",
+			Message: "No permission to move experiment into this course",
+		})
+		return
+	}
+	var groups []models.Group
+	if req.GroupIDs != nil {
+		courseID := experiment.CourseID
+		if req.CourseID != nil {
+			courseID = *req.CourseID
+		}
+		var err error
+		if groups, err = findGroups(db, req.GroupIDs, courseID); err != nil {
+			c.JSON(http.StatusBadRequest, UpdateExperimentResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if req.CourseID != nil && *req.CourseID != "" && *req.CourseID != experiment.CourseID {
+		if err := checkAssignmentsInCourse(db, experiment, *req.CourseID, req.GroupIDs == nil); err != nil {
+			c.JSON(http.StatusBadRequest, UpdateExperimentResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	// 只有实验创建者可以调整协作教师
+	var coTeachers []models.User
+	if req.CoTeacherIDs != nil {
+		if experiment.TeacherID != 0 && experiment.TeacherID != teacherID {
+			c.JSON(http.StatusForbidden, UpdateExperimentResponse{
+				Status:  "error",
+				Message: "Only the owner can change co-teachers",
+			})
+			return
+		}
+		var err error
+		if coTeachers, err = findCoTeachers(db, req.CoTeacherIDs, experiment.TeacherID); err != nil {
+			c.JSON(http.StatusBadRequest, UpdateExperimentResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
 
+	// applyQuestionUpdate 将提交的字段覆盖到题目输入上，未提交的字段保持原值
+	applyQuestionUpdate := func(input *QuestionInput, q UpdateQuestionInput) {
+		if q.Type != "" {
+			input.Type = q.Type
+		}
+		if q.Content != "" {
+			input.Content = q.Content
+		}
+		if q.Score > 0 {
+			input.Score = q.Score
+		}
+		if q.ImageURL != "" {
+			input.ImageURL = q.ImageURL
+		}
+		if q.Explanation != "" {
+			input.Explanation = q.Explanation
+		}
+		if q.Pool != nil {
+			input.Pool = *q.Pool
+		}
+		if q.Difficulty != "" {
+			input.Difficulty = q.Difficulty
+		}
+		if len(q.Options) > 0 {
+			input.Options = q.Options
+		}
+		if q.CorrectAnswer != "" {
+			input.CorrectAnswer = q.CorrectAnswer
+		}
+		if len(q.CorrectAnswers) > 0 {
+			input.CorrectAnswers = q.CorrectAnswers
+		}
+		if q.ScoringMode != "" {
+			input.ScoringMode = q.ScoringMode
+		}
+		if q.Tolerance != nil {
+			input.Tolerance = *q.Tolerance
+		}
+		if q.ToleranceType != "" {
+			input.ToleranceType = q.ToleranceType
+		}
+		if q.Unit != nil {
+			input.Unit = *q.Unit
+		}
+		// 修改填空配置但未给出参考答案时，参考答案按新配置重新生成
+		if q.BlankConfig != nil {
+			input.BlankConfig = q.BlankConfig
+			input.CorrectAnswer = q.CorrectAnswer
+		}
+		// 传空数组表示删除评分细则
+		if q.Rubric != nil {
+			input.Rubric = q.Rubric
+		}
+		if len(q.TestCases) > 0 {
+			input.TestCases = q.TestCases
+		}
+		if q.TimeLimit != nil {
+			input.TimeLimit = *q.TimeLimit
+		}
+		if q.MemoryLimitMB != nil {
+			input.MemoryLimitMB = *q.MemoryLimitMB
+		}
+		if q.LanguageLimits != nil {
+			input.LanguageLimits = q.LanguageLimits
+		}
+		if q.FloatEpsilon != nil {
+			input.FloatEpsilon = *q.FloatEpsilon
+		}
+		if q.StarterCode != nil {
+			input.StarterCode = q.StarterCode
+		}
+		if q.Harness != nil {
+			input.Harness = q.Harness
+		}
+		if q.ReferenceSolution != nil {
+			input.ReferenceSolution = q.ReferenceSolution
+		}
+		// 只修改比较方式为 checker 时保留原有检查程序，改为其他方式时清除
+		if q.CompareMode != "" || q.Checker != nil {
+			if q.Checker != nil || q.CompareMode != compareChecker {
+				input.Checker = q.Checker
+			}
+			input.CompareMode = q.CompareMode
+		}
+		input.ValidateTestCases = q.ValidateTestCases
+	}
+
+	var warnings []TestCaseWarning
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// 更新实验基本信息
 		if req.Title != "" {
@@ -1043,12 +1677,41 @@ func UpdateExperiment(c *gin.Context) {
 		if req.Permission != nil {
 			experiment.Permission = *req.Permission
 		}
+		if req.CourseID != nil {
+			experiment.CourseID = *req.CourseID
+		}
 		if !req.Deadline.IsZero() {
 			if req.Deadline.Before(time.Now()) {
 				return errors.New("deadline must be in the future")
 			}
 			experiment.Deadline = req.Deadline
 		}
+		if req.ClearOpensAt {
+			experiment.OpensAt = nil
+		} else if req.OpensAt != nil {
+			experiment.OpensAt = req.OpensAt
+		}
+		if req.Duration != nil {
+			experiment.DurationMinutes = *req.Duration
+		}
+		if req.MaxAttempts != nil {
+			experiment.MaxAttempts = *req.MaxAttempts
+		}
+		if req.ShuffleQuestions != nil {
+			experiment.ShuffleQuestions = *req.ShuffleQuestions
+		}
+		if req.ShuffleOptions != nil {
+			experiment.ShuffleOptions = *req.ShuffleOptions
+		}
+		if req.Policy != "" {
+			experiment.ScoringPolicy = req.Policy
+		}
+		if req.LatePolicy != nil {
+			req.LatePolicy.apply(&experiment)
+		}
+		if experiment.OpensAt != nil && !experiment.OpensAt.Before(experiment.Deadline) {
+			return errors.New("opens_at must be before deadline")
+		}
 
 		// 处理附件上传
 		form, err := c.MultipartForm()
@@ -1083,106 +1746,59 @@ func UpdateExperiment(c *gin.Context) {
 			existingQuestions[q.ID] = &experiment.Questions[i]
 		}
 
-		//var newQuestions []models.Question
 		for _, q := range req.Questions {
-			if q.QuestionID != "" {
-				// 更新现有题目
-				if question, exists := existingQuestions[q.QuestionID]; exists {
-					updated := false
-
-					if q.Content != "" {
-						question.Content = q.Content
-						updated = true
-					}
-					if q.CorrectAnswer != "" {
-						question.CorrectAnswer = q.CorrectAnswer
-						updated = true
-					}
-					if q.Score > 0 {
-						question.Score = q.Score
-						updated = true
-					}
-					if q.Type != "" {
-						question.Type = q.Type
-						updated = true
-					}
-					if q.ImageURL != "" {
-						question.ImageURL = q.ImageURL
-						updated = true
-					}
-					if q.Explanation != "" {
-						question.Explanation = q.Explanation
-						updated = true
-					}
-					if len(q.Options) > 0 {
-						optionsJSON, err := json.Marshal(q.Options)
-						if err != nil {
-							c.JSON(http.StatusInternalServerError, UpdateExperimentResponse{
-								Status:  "error",
-								Message: "Unable to serialize options",
-							})
-							return err
-						}
-						question.Options = string(optionsJSON)
-						updated = true
-					}
-					if len(q.TestCases) > 0 {
-						testCasesJSON, err := json.Marshal(q.TestCases)
-						if err != nil {
-							c.JSON(http.StatusInternalServerError, UpdateExperimentResponse{
-								Status:  "error",
-								Message: "Unable to serialize test cases",
-							})
-							return err
-						}
-						question.TestCases = string(testCasesJSON)
-						updated = true
-					}
-					// 显式保存更改到数据库
-					if updated {
-						if err := tx.Save(question).Error; err != nil {
-							c.JSON(http.StatusInternalServerError, UpdateExperimentResponse{
-								Status:  "error",
-								Message: "Failed to update question " + question.ID,
-							})
-							return err
-						}
-					}
-				} else {
-					return fmt.Errorf("question %s not found", q.QuestionID)
-				}
-			} else {
+			if q.QuestionID == "" {
 				// 新增题目
-				newQ := models.Question{
-					ID:           uuid.NewString(),
-					ExperimentID: experimentID,
-					Type:         q.Type,
-					Content:      q.Content,
-					Score:        q.Score,
-					ImageURL:     q.ImageURL,
-					Explanation:  q.Explanation,
-				}
-				if q.Type == "choice" {
-					optionsJSON, err := json.Marshal(q.Options)
-					if err != nil {
-						return fmt.Errorf("failed to serialize options: %w", err)
-					}
-					newQ.Options = string(optionsJSON)
-					newQ.CorrectAnswer = q.CorrectAnswer
-				}
-				if q.Type == "blank" {
-					newQ.CorrectAnswer = q.CorrectAnswer
+				input := QuestionInput{}
+				applyQuestionUpdate(&input, q)
+				newQ, warning, err := buildQuestion(uuid.NewString(), experimentID, input)
+				if err != nil {
+					return err
 				}
-				if q.Type == "code" && len(q.TestCases) > 0 {
-					testCasesJSON, _ := json.Marshal(q.TestCases)
-					newQ.TestCases = string(testCasesJSON)
+				if warning != nil {
+					warnings = append(warnings, *warning)
 				}
 				if err := tx.Create(&newQ).Error; err != nil {
 					return fmt.Errorf("failed to create new question: %w", err)
 				}
 				experiment.Questions = append(experiment.Questions, newQ)
+				continue
+			}
+			// 更新现有题目：在原有内容上覆盖提交的字段，再按新增题目的规则重新校验
+			question, exists := existingQuestions[q.QuestionID]
+			if !exists {
+				return fmt.Errorf("question %s not found", q.QuestionID)
+			}
+			input := questionInput(*question)
+			applyQuestionUpdate(&input, q)
+			updated, warning, err := buildQuestion(question.ID, experimentID, input)
+			if err != nil {
+				return err
 			}
+			if warning != nil {
+				warnings = append(warnings, *warning)
+			}
+			updated.BankQuestionID = question.BankQuestionID
+			updated.CreatedAt, updated.UpdatedAt = question.CreatedAt, question.UpdatedAt
+			if updated == *question {
+				continue
+			}
+			*question = updated
+			if err := tx.Save(question).Error; err != nil {
+				return fmt.Errorf("failed to update question %s: %w", question.ID, err)
+			}
+		}
+		// 从题库复制新增题目
+		bankQuestions, err := copyBankQuestions(tx, req.BankQuestionIDs, teacherID, experimentID)
+		if err != nil {
+			return err
 		}
+		for i := range bankQuestions {
+			if err := tx.Create(&bankQuestions[i]).Error; err != nil {
+				return fmt.Errorf("failed to create question from bank: %w", err)
+			}
+		}
+		experiment.Questions = append(experiment.Questions, bankQuestions...)
 		// 删除题目，并从 experiment.Questions 中移除
 		var remainingQuestions []models.Question
 		for _, question := range experiment.Questions {
@@ -1209,6 +1825,33 @@ func UpdateExperiment(c *gin.Context) {
 			}
 		}
 		experiment.Questions = remainingQuestions
+		// 抽题规则整体替换，已开始作答的学生沿用原来的抽题结果
+		if req.Pools != nil {
+			if err := tx.Where("experiment_id = ?", experimentID).Delete(&models.QuestionPool{}).Error; err != nil {
+				return fmt.Errorf("failed to replace pools: %w", err)
+			}
+			experiment.Pools = toQuestionPools(req.Pools, experimentID)
+			if len(experiment.Pools) > 0 {
+				if err := tx.Create(&experiment.Pools).Error; err != nil {
+					return fmt.Errorf("failed to replace pools: %w", err)
+				}
+			}
+		} else if err := tx.Where("experiment_id = ?", experimentID).Find(&experiment.Pools).Error; err != nil {
+			return fmt.Errorf("failed to load pools: %w", err)
+		}
+		if err := validatePools(experiment.Questions, experiment.Pools); err != nil {
+			return err
+		}
+		if req.CoTeacherIDs != nil {
+			if err := tx.Model(&experiment).Association("CoTeachers").Replace(coTeachers); err != nil {
+				return fmt.Errorf("failed to update co-teachers: %w", err)
+			}
+		}
+		if req.GroupIDs != nil {
+			if err := tx.Model(&experiment).Association("Groups").Replace(groups); err != nil {
+				return fmt.Errorf("failed to update groups: %w", err)
+			}
+		}
 		experiment.UpdatedAt = time.Now()
 		// 保存实验本体
 		if err := tx.Save(&experiment).Error; err != nil {
@@ -1216,8 +1859,6 @@ func UpdateExperiment(c *gin.Context) {
 		}
 		return nil
 	})
-	//更新experiment_submissions表中对应实验状态为in_progress
-	db.Model(&models.ExperimentSubmission{}).Where("experiment_id = ?", experimentID).Update("status", "in_progress")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, UpdateExperimentResponse{
 			Status:  "error",
@@ -1228,6 +1869,7 @@ func UpdateExperiment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, UpdateExperimentResponse{
 		Status:       "success",
+		Warnings:     warnings,
 		ExperimentID: experiment.ID,
 		Title:        experiment.Title,
 		UpdatedAt:    experiment.UpdatedAt,
@@ -1240,7 +1882,7 @@ func DeleteExperiment(c *gin.Context) {
 
 	// 验证用户角色
 	user, _ := c.Get("user")
-	if user.(models.User).Role != "teacher" {
+	if user.(models.User).Role != "teacher" && !isAdmin(user.(models.User)) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  "error",
 			"message": "仅允许教师操作",
@@ -1268,6 +1910,13 @@ func DeleteExperiment(c *gin.Context) {
 		})
 		return
 	}
+	if !canManageExperiment(db, experiment, user.(models.User)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "无权删除该实验",
+		})
+		return
+	}
 	tx := db.Begin()
 	// 0. 删除关联表中的记录（新增这一步）
 	if err := tx.Table("experiment_users").
@@ -1280,6 +1929,44 @@ func DeleteExperiment(c *gin.Context) {
 		})
 		return
 	}
+	if err := tx.Table("experiment_teachers").
+		Where("experiment_id = ?", experimentID).
+		Delete(nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "删除实验协作教师关联失败",
+		})
+		return
+	}
+	if err := tx.Table("experiment_groups").
+		Where("experiment_id = ?", experimentID).
+		Delete(nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "删除实验分组关联失败",
+		})
+		return
+	}
+	if err := tx.Where("experiment_id = ?", experimentID).
+		Delete(&models.QuestionPool{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "删除抽题规则失败",
+		})
+		return
+	}
+	if err := tx.Where("experiment_id = ?", experimentID).
+		Delete(&models.DeadlineExtension{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "删除学生延期记录失败",
+		})
+		return
+	}
 	// 1. 删除关联的题目提交记录
 	if err := tx.Where("submission_id IN (SELECT id FROM experiment_submissions WHERE experiment_id = ?)", experimentID).
 		Delete(&models.QuestionSubmission{}).Error; err != nil {
@@ -1345,14 +2032,32 @@ func CreateNotification(c *gin.Context) {
 		Title        string `json:"title" binding:"required"`
 		Content      string `json:"content" binding:"required"`
 		ExperimentID string `json:"experiment_id"`
+		CourseID     string `json:"course_id"`
 		IsImportant  bool   `json:"is_important"`
-		Users        []uint `json:"users"` // 用户 ID 列表
+		Users        []uint `json:"users"`  // 用户 ID 列表，为空且指定课程时发给全部选课学生
+		Groups       []uint `json:"groups"` // 分组 ID 列表，分组成员动态接收
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	teacherID := currentUser(c).ID
+	if req.ExperimentID != "" {
+		var experiment models.Experiment
+		if err := global.DB.First(&experiment, "id = ?", req.ExperimentID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+			return
+		}
+		if !canManageExperiment(global.DB, experiment, currentUser(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No permission to notify for this experiment"})
+			return
+		}
+	}
+	if req.CourseID != "" && !canManageCourse(global.DB, req.CourseID, teacherID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No permission to notify for this course"})
+		return
+	}
 
 	// ✅ 先查找用户（不是先赋值到 notification）
 	var students []models.User
@@ -1361,6 +2066,29 @@ func CreateNotification(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch target students"})
 			return
 		}
+	} else if req.CourseID != "" && len(req.Groups) == 0 {
+		enrolled := global.DB.Table("course_students").Select("user_id").Where("course_id = ?", req.CourseID)
+		if err := global.DB.Where("id IN (?)", enrolled).Find(&students).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch target students"})
+			return
+		}
+	}
+
+	// 与创建实验相同：分组必须存在且属于指定课程，教师还需能管理分组所属的课程
+	groupIDs := make([]string, len(req.Groups))
+	for i, id := range req.Groups {
+		groupIDs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	groups, err := findGroups(global.DB, groupIDs, req.CourseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, g := range groups {
+		if g.CourseID != "" && !canManageCourse(global.DB, g.CourseID, teacherID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No permission to notify group " + g.Name})
+			return
+		}
 	}
 
 	// ✅ 再构造 notification，赋值 Users 为 []User
@@ -1369,9 +2097,12 @@ func CreateNotification(c *gin.Context) {
 		Title:        req.Title,
 		Content:      req.Content,
 		ExperimentID: req.ExperimentID,
+		CourseID:     req.CourseID,
 		IsImportant:  req.IsImportant,
+		TeacherID:    teacherID,
 		CreatedAt:    time.Now(),
 		Users:        students, // ✅ 正确类型
+		Groups:       groups,
 	}
 
 	// ✅ 此时才保存到数据库
@@ -1393,6 +2124,7 @@ func GetTeacherNotifications(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 	experimentID := c.Query("experiment_id")
+	courseID := c.Query("course_id")
 	isImportantStr := c.Query("is_important")
 	createdAfter := c.Query("created_after")
 
@@ -1407,13 +2139,20 @@ func GetTeacherNotifications(c *gin.Context) {
 		return
 	}
 
-	// 构建查询
-	query := global.DB.Model(&models.Notification{}).Order("created_at DESC")
+	// 构建查询：仅返回本人发布或所管理实验的通知
+	teacherID := currentUser(c).ID
+	managedExperiments := manageableExperiments(global.DB.Model(&models.Experiment{}), currentUser(c)).Select("experiments.id")
+	query := global.DB.Model(&models.Notification{}).
+		Where("teacher_id = ? OR experiment_id IN (?)", teacherID, managedExperiments).
+		Order("created_at DESC")
 
 	// 筛选条件
 	if experimentID != "" {
 		query = query.Where("experiment_id = ?", experimentID)
 	}
+	if courseID != "" {
+		query = query.Where("course_id = ?", courseID)
+	}
 	if isImportantStr != "" {
 		isImportant, err := strconv.ParseBool(isImportantStr)
 		if err == nil {
@@ -1515,6 +2254,15 @@ func HandleTeacherUpload(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Experiment ID is required"})
 		return
 	}
+	var experiment models.Experiment
+	if err := global.DB.First(&experiment, "id = ?", experimentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return
+	}
+	if !canManageExperiment(global.DB, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No permission to upload files for this experiment"})
+		return
+	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -1564,6 +2312,15 @@ func TeacherDeleteFile(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
 		return
 	}
+	var experiment models.Experiment
+	if err := global.DB.First(&experiment, "id = ?", experimentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return
+	}
+	if !canManageExperiment(global.DB, experiment, currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No permission to delete files for this experiment"})
+		return
+	}
 
 	// 使用从参数中获取的、已解码的文件名
 	// filepath.Base() 只是为了安全，去除路径部分
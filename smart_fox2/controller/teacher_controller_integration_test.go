@@ -29,6 +29,9 @@ func setupTestDB() *gorm.DB {
 		&models.QuestionSubmission{},
 		&models.Notification{},
 		&models.Attachment{},
+		&models.Group{},
+		&models.DeadlineExtension{},
+		&models.QuestionPool{},
 	)
 	global.DB = db
 	return db
@@ -66,6 +69,7 @@ func TestTeacherWorkflow(t *testing.T) {
 		Permission:  1,
 		Deadline:    time.Now().Add(24 * time.Hour),
 		CreatedAt:   time.Now(),
+		TeacherID:   teacher.ID,
 		Users:       []models.User{student},
 		Questions: []models.Question{
 			{ID: "q1", Type: "choice", Content: "1+1=?", Options: `["2","3"]`, CorrectAnswer: "2", Score: 5},
@@ -76,6 +80,7 @@ func TestTeacherWorkflow(t *testing.T) {
 	// -------- 获取实验详情 --------
 	w1 := httptest.NewRecorder()
 	c1, _ := gin.CreateTestContext(w1)
+	c1.Set("user", teacher)
 	c1.Params = []gin.Param{{Key: "experiment_id", Value: "exp1"}}
 	c1.Request = httptest.NewRequest("GET", "/experiments/exp1", nil)
 	controller.GetExperimentDetail_Teacher(c1)
@@ -115,6 +120,7 @@ func TestTeacherWorkflow(t *testing.T) {
 	// -------- 获取学生提交（只测试能找到的空记录，不测试提交新实验） --------
 	w4 := httptest.NewRecorder()
 	c4, _ := gin.CreateTestContext(w4)
+	c4.Set("user", teacher)
 	c4.Params = []gin.Param{
 		{Key: "experiment_id", Value: "exp1"},
 		{Key: "student_id", Value: strconv.Itoa(int(student.ID))},
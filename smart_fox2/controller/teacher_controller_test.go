@@ -2,7 +2,7 @@ package controller
 
 import (
 	"bytes"
-	//"encoding/json"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -32,6 +32,10 @@ func setupTestDBTeacher(t *testing.T) {
 		&models.QuestionSubmission{},
 		&models.Notification{},
 		&models.Attachment{},
+		&models.Group{},
+		&models.DeadlineExtension{},
+		&models.BankQuestion{},
+		&models.QuestionPool{},
 	)
 	if err != nil {
 		t.Fatalf("migrate failed: %v", err)
@@ -66,12 +70,18 @@ func TestGetExperimentStatus(t *testing.T) {
 	future := time.Now().Add(time.Hour)
 	past := time.Now().Add(-time.Hour)
 
-	if got := getExperimentStatus(future); got != "active" {
+	if got := getExperimentStatus(nil, future); got != "active" {
 		t.Errorf("expected active, got %s", got)
 	}
-	if got := getExperimentStatus(past); got != "expired" {
+	if got := getExperimentStatus(nil, past); got != "expired" {
 		t.Errorf("expected expired, got %s", got)
 	}
+	if got := getExperimentStatus(&future, future.Add(time.Hour)); got != "upcoming" {
+		t.Errorf("expected upcoming, got %s", got)
+	}
+	if got := getExperimentStatus(&past, future); got != "active" {
+		t.Errorf("expected active, got %s", got)
+	}
 }
 
 // GetExperimentDetail_Teacher
@@ -79,6 +89,7 @@ func TestGetExperimentDetail_Teacher(t *testing.T) {
 	setupTestDBTeacher(t)
 
 	stu := createTestUser(t, "student")
+	teacher := createTestUser(t, "teacher")
 
 	exp := models.Experiment{
 		ID:          "exp1",
@@ -87,6 +98,7 @@ func TestGetExperimentDetail_Teacher(t *testing.T) {
 		Permission:  1,
 		Deadline:    time.Now().Add(24 * time.Hour),
 		CreatedAt:   time.Now(),
+		TeacherID:   teacher.ID,
 		Users:       []models.User{stu},
 		Questions: []models.Question{
 			{ID: "q1", Type: "choice", Content: "2+2=?", Options: `["4","5"]`, CorrectAnswer: "4", Score: 5},
@@ -96,6 +108,7 @@ func TestGetExperimentDetail_Teacher(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
 	c.Params = []gin.Param{{Key: "experiment_id", Value: "exp1"}}
 	c.Request = httptest.NewRequest("GET", "/experiments/exp1", nil)
 
@@ -110,13 +123,15 @@ func TestGetExperimentDetail_Teacher(t *testing.T) {
 func TestGetStudentSubmissions_NotStarted(t *testing.T) {
 	setupTestDBTeacher(t)
 	stu := createTestUser(t, "student")
+	teacher := createTestUser(t, "teacher")
 
-	exp := models.Experiment{ID: "exp2", Title: "实验二"}
+	exp := models.Experiment{ID: "exp2", Title: "实验二", TeacherID: teacher.ID}
 	global.DB.Create(&exp)
 	global.DB.Model(&exp).Association("Users").Append(&stu)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
 	c.Params = []gin.Param{
 		{Key: "experiment_id", Value: "exp2"},
 		{Key: "student_id", Value: strconv.Itoa(int(stu.ID))},
@@ -145,6 +160,79 @@ func TestUpdateExperiment_BadRequest(t *testing.T) {
 	}
 }
 
+// 实验移入其他课程时，已分配的学生和分组须属于新课程
+func TestUpdateExperiment_CourseChangeChecksAssignments(t *testing.T) {
+	setupTestDBTeacher(t)
+	global.DB.AutoMigrate(&models.Course{})
+	teacher := createTestUser(t, "teacher")
+	enrolled := createTestUser(t, "student")
+	outsider := createTestUser(t, "student")
+	global.DB.Create(&models.Course{ID: "course-a", Name: "课程A", Teachers: []models.User{teacher}})
+	global.DB.Create(&models.Course{ID: "course-b", Name: "课程B", Teachers: []models.User{teacher}, Students: []models.User{enrolled}})
+	groupA := models.Group{Name: "A组", CourseID: "course-a"}
+	groupB := models.Group{Name: "B组", CourseID: "course-b"}
+	global.DB.Create(&groupA)
+	global.DB.Create(&groupB)
+
+	update := func(expID, body string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		c.Params = []gin.Param{{Key: "experiment_id", Value: expID}}
+		c.Request = httptest.NewRequest("PUT", "/experiments/"+expID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		UpdateExperiment(c)
+		return w.Code
+	}
+	deadline := time.Now().Add(time.Hour)
+	global.DB.Create(&models.Experiment{ID: "exp-users", Title: "学生", TeacherID: teacher.ID, CourseID: "course-a", Deadline: deadline,
+		Users: []models.User{enrolled, outsider}})
+	global.DB.Create(&models.Experiment{ID: "exp-groups", Title: "分组", TeacherID: teacher.ID, CourseID: "course-a", Deadline: deadline,
+		Users: []models.User{enrolled}, Groups: []models.Group{groupA}})
+
+	if code := update("exp-users", `{"course_id":"course-b"}`); code != http.StatusBadRequest {
+		t.Errorf("unenrolled student: expected 400, got %d", code)
+	}
+	if code := update("exp-groups", `{"course_id":"course-b"}`); code != http.StatusBadRequest {
+		t.Errorf("group from old course: expected 400, got %d", code)
+	}
+	// 同时替换为新课程的分组时可以移动
+	if code := update("exp-groups", `{"course_id":"course-b","group_ids":["`+strconv.Itoa(int(groupB.ID))+`"]}`); code != http.StatusOK {
+		t.Errorf("groups replaced: expected 200, got %d", code)
+	}
+	var exp models.Experiment
+	global.DB.First(&exp, "id = ?", "exp-users")
+	if exp.CourseID != "course-a" {
+		t.Errorf("rejected update should keep course, got %s", exp.CourseID)
+	}
+}
+
+// clear_opens_at 取消已设置的开放时间
+func TestUpdateExperiment_ClearOpensAt(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	opensAt := time.Now().Add(time.Hour)
+	global.DB.Create(&models.Experiment{ID: "exp-open", Title: "定时开放", TeacherID: teacher.ID,
+		OpensAt: &opensAt, Deadline: time.Now().Add(2 * time.Hour)})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", teacher)
+	c.Params = []gin.Param{{Key: "experiment_id", Value: "exp-open"}}
+	c.Request = httptest.NewRequest("PUT", "/experiments/exp-open", bytes.NewBufferString(`{"clear_opens_at":true}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	UpdateExperiment(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var exp models.Experiment
+	global.DB.First(&exp, "id = ?", "exp-open")
+	if exp.OpensAt != nil {
+		t.Errorf("expected opens_at cleared, got %v", exp.OpensAt)
+	}
+}
+
 // DeleteExperiment
 func TestDeleteExperiment_Forbidden(t *testing.T) {
 	setupTestDBTeacher(t)
@@ -179,6 +267,42 @@ func TestCreateNotification(t *testing.T) {
 	}
 }
 
+// 通知的目标分组须存在、属于指定课程，且教师能管理分组所属的课程
+func TestCreateNotification_Groups(t *testing.T) {
+	setupTestDBTeacher(t)
+	global.DB.AutoMigrate(&models.Course{})
+	teacher := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+	global.DB.Create(&models.Course{ID: "course-a", Name: "课程A", Teachers: []models.User{teacher}})
+	global.DB.Create(&models.Course{ID: "course-b", Name: "课程B", Teachers: []models.User{other}})
+	own := models.Group{Name: "本课程分组", CourseID: "course-a"}
+	foreign := models.Group{Name: "他人分组", CourseID: "course-b"}
+	global.DB.Create(&own)
+	global.DB.Create(&foreign)
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`"course_id":"course-a","groups":[` + strconv.Itoa(int(own.ID)) + `]`, http.StatusCreated},
+		{`"course_id":"course-a","groups":[` + strconv.Itoa(int(foreign.ID)) + `]`, http.StatusBadRequest},
+		{`"groups":[9999]`, http.StatusBadRequest},
+		{`"groups":[` + strconv.Itoa(int(foreign.ID)) + `]`, http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", teacher)
+		body := `{"title":"公告","content":"内容",` + tc.body + `}`
+		c.Request = httptest.NewRequest("POST", "/notifications", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		CreateNotification(c)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.body, tc.code, w.Code)
+		}
+	}
+}
+
 // GetTeacherNotifications
 func TestGetTeacherNotifications(t *testing.T) {
 	setupTestDBTeacher(t)
@@ -194,3 +318,176 @@ func TestGetTeacherNotifications(t *testing.T) {
 		t.Errorf("expected 200, got %d", w.Code)
 	}
 }
+
+// 非创建者、非协作教师不能删除实验
+func TestDeleteExperiment_NotOwner(t *testing.T) {
+	setupTestDBTeacher(t)
+	owner := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+
+	exp := models.Experiment{ID: "exp-own", Title: "归属实验", TeacherID: owner.ID}
+	global.DB.Create(&exp)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", other)
+	c.Params = []gin.Param{{Key: "experiment_id", Value: "exp-own"}}
+	c.Request = httptest.NewRequest("DELETE", "/experiments/exp-own", nil)
+
+	DeleteExperiment(c)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+	var count int64
+	global.DB.Model(&models.Experiment{}).Where("id = ?", "exp-own").Count(&count)
+	if count != 1 {
+		t.Errorf("experiment should not be deleted")
+	}
+}
+
+// 协作教师可以查看实验详情
+func TestGetExperimentDetail_Teacher_CoTeacher(t *testing.T) {
+	setupTestDBTeacher(t)
+	owner := createTestUser(t, "teacher")
+	coTeacher := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+
+	exp := models.Experiment{
+		ID:         "exp-co",
+		Title:      "协作实验",
+		TeacherID:  owner.ID,
+		Deadline:   time.Now().Add(time.Hour),
+		CoTeachers: []models.User{coTeacher},
+	}
+	global.DB.Create(&exp)
+
+	for _, tc := range []struct {
+		user models.User
+		code int
+	}{
+		{owner, http.StatusOK},
+		{coTeacher, http.StatusOK},
+		{other, http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", tc.user)
+		c.Params = []gin.Param{{Key: "experiment_id", Value: "exp-co"}}
+		c.Request = httptest.NewRequest("GET", "/experiments/exp-co", nil)
+
+		GetExperimentDetail_Teacher(c)
+		if w.Code != tc.code {
+			t.Errorf("user %s: expected %d, got %d", tc.user.Name, tc.code, w.Code)
+		}
+	}
+}
+
+// 实验列表只返回本人创建或协作的实验
+func TestGetExperiments_Teacher_Scoped(t *testing.T) {
+	setupTestDBTeacher(t)
+	owner := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+
+	global.DB.Create(&models.Experiment{ID: "exp-a", Title: "A", TeacherID: owner.ID, CreatedAt: time.Now()})
+	global.DB.Create(&models.Experiment{ID: "exp-b", Title: "B", TeacherID: other.ID, CreatedAt: time.Now()})
+	global.DB.Create(&models.Experiment{ID: "exp-c", Title: "C", TeacherID: other.ID, CreatedAt: time.Now(), CoTeachers: []models.User{owner}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", owner)
+	c.Request = httptest.NewRequest("GET", "/experiments?page=1&limit=10", nil)
+
+	GetExperiments_Teacher(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp struct {
+		Data []struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 experiments, got %d", len(resp.Data))
+	}
+	for _, e := range resp.Data {
+		if e.ExperimentID == "exp-b" {
+			t.Errorf("experiment of another teacher should not be listed")
+		}
+	}
+}
+
+// 没有归属教师的历史实验只有管理员可以管理
+func TestLegacyExperiment_AdminOnly(t *testing.T) {
+	setupTestDBTeacher(t)
+	teacher := createTestUser(t, "teacher")
+	admin := createTestUser(t, "admin")
+	global.DB.Create(&models.Experiment{ID: "exp-legacy", Title: "历史实验", Deadline: time.Now().Add(time.Hour), CreatedAt: time.Now()})
+
+	for _, tc := range []struct {
+		user   models.User
+		code   int
+		listed int
+	}{
+		{teacher, http.StatusForbidden, 0},
+		{admin, http.StatusOK, 1},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", tc.user)
+		c.Params = []gin.Param{{Key: "experiment_id", Value: "exp-legacy"}}
+		c.Request = httptest.NewRequest("GET", "/experiments/exp-legacy", nil)
+		GetExperimentDetail_Teacher(c)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.user.Role, tc.code, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Set("user", tc.user)
+		c.Request = httptest.NewRequest("GET", "/experiments?page=1&limit=10", nil)
+		GetExperiments_Teacher(c)
+		var resp struct {
+			Data []struct {
+				ExperimentID string `json:"experiment_id"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Data) != tc.listed {
+			t.Errorf("%s: expected %d experiments listed, got %d", tc.user.Role, tc.listed, len(resp.Data))
+		}
+	}
+}
+
+// 补全历史实验的创建者：课程唯一教师优先，其次为最早发布该实验通知的教师
+func TestBackfillExperimentOwners(t *testing.T) {
+	setupTestDBTeacher(t)
+	global.DB.AutoMigrate(&models.Course{})
+	courseTeacher := createTestUser(t, "teacher")
+	notifier := createTestUser(t, "teacher")
+	other := createTestUser(t, "teacher")
+
+	global.DB.Create(&models.Course{ID: "course-1", Name: "数据结构", Teachers: []models.User{courseTeacher}})
+	global.DB.Create(&models.Experiment{ID: "exp-course", Title: "课程实验", CourseID: "course-1"})
+	global.DB.Create(&models.Experiment{ID: "exp-notified", Title: "发过通知的实验"})
+	global.DB.Create(&models.Experiment{ID: "exp-unknown", Title: "无法确定的实验"})
+	global.DB.Create(&models.Experiment{ID: "exp-owned", Title: "已有归属的实验", TeacherID: other.ID})
+	global.DB.Create(&models.Notification{ID: "n-2", ExperimentID: "exp-notified", TeacherID: other.ID, CreatedAt: time.Now()})
+	global.DB.Create(&models.Notification{ID: "n-1", ExperimentID: "exp-notified", TeacherID: notifier.ID, CreatedAt: time.Now().Add(-time.Hour)})
+
+	if err := BackfillExperimentOwners(global.DB); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	for id, owner := range map[string]uint{
+		"exp-course":   courseTeacher.ID,
+		"exp-notified": notifier.ID,
+		"exp-unknown":  0,
+		"exp-owned":    other.ID,
+	} {
+		var exp models.Experiment
+		global.DB.First(&exp, "id = ?", id)
+		if exp.TeacherID != owner {
+			t.Errorf("%s: expected owner %d, got %d", id, owner, exp.TeacherID)
+		}
+	}
+}
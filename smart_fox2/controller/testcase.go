@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"lh/models"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusAccepted 评测服务返回的用例通过状态
+const statusAccepted = "Accepted"
+
+// CaseResult 单个测试用例的评测结果，只有公开样例才记录输入、期望输出和实际输出
+type CaseResult struct {
+	Index          int         `json:"index"`
+	Group          string      `json:"group,omitempty"`
+	Weight         float64     `json:"weight"`
+	Status         string      `json:"status"`
+	Passed         bool        `json:"passed"`
+	Message        string      `json:"message,omitempty"` // 检查程序给出的提示信息
+	Sample         bool        `json:"sample,omitempty"`
+	Input          interface{} `json:"input,omitempty"`
+	ExpectedOutput interface{} `json:"expected_output,omitempty"`
+	Stdout         string      `json:"stdout,omitempty"`
+}
+
+// SubtaskResult 子任务的评测结果，组内用例全部通过才得分
+type SubtaskResult struct {
+	Group  string  `json:"group"`
+	Weight float64 `json:"weight"`
+	Cases  int     `json:"cases"`
+	Passed bool    `json:"passed"`
+}
+
+// JudgeResult 代码题的评测明细，保存在答案中供学生和教师查看
+type JudgeResult struct {
+	Cases    []CaseResult    `json:"cases"`
+	Subtasks []SubtaskResult `json:"subtasks,omitempty"`
+}
+
+// caseWeight 测试用例的权重，未设置时按 1 计
+func (tc TestCase) caseWeight() float64 {
+	if tc.Weight > 0 {
+		return tc.Weight
+	}
+	return 1
+}
+
+// sampleCases 题目中向学生公开的样例，只包含输入和期望输出
+func sampleCases(question models.Question) []gin.H {
+	var testCases []TestCase
+	json.Unmarshal([]byte(question.TestCases), &testCases)
+	samples := make([]gin.H, 0)
+	for _, tc := range testCases {
+		if tc.Sample {
+			samples = append(samples, gin.H{"input": tc.Input, "expected_output": tc.ExpectedOutput})
+		}
+	}
+	return samples
+}
+
+// parseJudgeResult 读取单题答案的评测明细，未评测时返回 nil
+func parseJudgeResult(answer models.QuestionSubmission) *JudgeResult {
+	var result JudgeResult
+	if answer.JudgeResult == "" || json.Unmarshal([]byte(answer.JudgeResult), &result) != nil {
+		return nil
+	}
+	return &result
+}
+
+// codeScore 评测代码题的测试用例，返回自动评分部分的得分、反馈和评测明细
+func codeScore(question models.Question, response *EvaluationResponse) (int, string, JudgeResult) {
+	var testCases []TestCase
+	json.Unmarshal([]byte(question.TestCases), &testCases)
+
+	var result JudgeResult
+	subtaskIndex := make(map[string]int)
+	passedCases := 0
+	for i, tc := range testCases {
+		caseResult := CaseResult{Index: i + 1, Group: tc.Group, Weight: tc.caseWeight(), Sample: tc.Sample}
+		if i < len(response.CaseResults) {
+			caseResult.Status = response.CaseResults[i].Status
+		}
+		// 隐藏用例不向学生透露输入、输出和检查器信息
+		if tc.Sample {
+			caseResult.Input = tc.Input
+			caseResult.ExpectedOutput = tc.ExpectedOutput
+			if i < len(response.CaseResults) {
+				caseResult.Stdout = response.CaseResults[i].Stdout
+				caseResult.Message = response.CaseResults[i].CheckerMessage
+			}
+		}
+		caseResult.Passed = caseResult.Status == statusAccepted
+		if caseResult.Passed {
+			passedCases++
+		}
+		result.Cases = append(result.Cases, caseResult)
+
+		if tc.Group == "" {
+			continue
+		}
+		j, ok := subtaskIndex[tc.Group]
+		if !ok {
+			j = len(result.Subtasks)
+			subtaskIndex[tc.Group] = j
+			result.Subtasks = append(result.Subtasks, SubtaskResult{Group: tc.Group, Passed: true})
+		}
+		result.Subtasks[j].Weight += caseResult.Weight
+		result.Subtasks[j].Cases++
+		result.Subtasks[j].Passed = result.Subtasks[j].Passed && caseResult.Passed
+	}
+
+	score := judgeResultScore(question, result)
+	feedback := fmt.Sprintf("Passed %d/%d test cases", passedCases, len(testCases))
+	if len(result.Subtasks) > 0 {
+		parts := make([]string, len(result.Subtasks))
+		for i, subtask := range result.Subtasks {
+			verdict := "Failed"
+			if subtask.Passed {
+				verdict = "Passed"
+			}
+			parts[i] = fmt.Sprintf("Subtask %s: %s", subtask.Group, verdict)
+		}
+		feedback += "; " + strings.Join(parts, "; ")
+	}
+	return score, feedback, result
+}
+
+// judgeResultScore 按评测明细计算代码题自动评分部分的得分。
+// 未分组的用例各自按权重得分；同组用例构成一个子任务，全部通过才获得该组权重之和。
+// 得分按通过的权重比例向下取整，只有全部通过才能得到满分。
+func judgeResultScore(question models.Question, result JudgeResult) int {
+	var earned, total float64
+	for _, caseResult := range result.Cases {
+		if caseResult.Group != "" {
+			continue
+		}
+		total += caseResult.Weight
+		if caseResult.Passed {
+			earned += caseResult.Weight
+		}
+	}
+	for _, subtask := range result.Subtasks {
+		total += subtask.Weight
+		if subtask.Passed {
+			earned += subtask.Weight
+		}
+	}
+
+	score := 0
+	maxScore := autoGradedScore(question)
+	if total > 0 {
+		// 加上极小量避免 0.1+0.2 这类浮点误差使整分被向下取整
+		score = int(math.Floor(float64(maxScore)*earned/total + 1e-9))
+	}
+	return score
+}
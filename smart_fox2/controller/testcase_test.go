@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"lh/global"
+	"lh/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func judgeResponse(statuses ...string) *EvaluationResponse {
+	var resp EvaluationResponse
+	for _, status := range statuses {
+		resp.CaseResults = append(resp.CaseResults, EvaluationCaseResult{Status: status})
+	}
+	return &resp
+}
+
+func TestCodeScore_Weights(t *testing.T) {
+	question := models.Question{Type: "code", Score: 10,
+		TestCases: `[{"input":"1","expected_output":"1","weight":3},{"input":"2","expected_output":"2"},{"input":"3","expected_output":"3"}]`}
+
+	score, feedback, result := codeScore(question, judgeResponse(statusAccepted, "Wrong Answer", statusAccepted))
+	// 通过权重 4/5，得 8 分
+	assert.Equal(t, 8, score)
+	assert.Equal(t, "Passed 2/3 test cases", feedback)
+	assert.Len(t, result.Cases, 3)
+	assert.Empty(t, result.Subtasks)
+
+	// 得分一律向下取整：2/3 的 10 分为 6 分
+	question.TestCases = `[{"input":"1","expected_output":"1"},{"input":"2","expected_output":"2"},{"input":"3","expected_output":"3"}]`
+	score, _, _ = codeScore(question, judgeResponse(statusAccepted, statusAccepted, "Time Limit Exceeded"))
+	assert.Equal(t, 6, score)
+
+	// 未全部通过时得不到满分，999/1000 的 10 分为 9 分
+	question.TestCases = `[{"input":"1","expected_output":"1","weight":999},{"input":"2","expected_output":"2"}]`
+	score, _, _ = codeScore(question, judgeResponse(statusAccepted, "Wrong Answer"))
+	assert.Equal(t, 9, score)
+
+	// 能整除时不受浮点误差影响
+	question.TestCases = `[{"input":"1","expected_output":"1","weight":0.1},{"input":"2","expected_output":"2","weight":0.2},{"input":"3","expected_output":"3","weight":0.7}]`
+	score, _, _ = codeScore(question, judgeResponse(statusAccepted, statusAccepted, "Wrong Answer"))
+	assert.Equal(t, 3, score)
+}
+
+func TestCodeScore_Subtasks(t *testing.T) {
+	question := models.Question{Type: "code", Score: 12, TestCases: `[
+		{"input":"1","expected_output":"1","group":"小数据"},
+		{"input":"2","expected_output":"2","group":"小数据"},
+		{"input":"3","expected_output":"3","group":"大数据","weight":2},
+		{"input":"4","expected_output":"4","group":"大数据","weight":2},
+		{"input":"5","expected_output":"5","weight":2}]`}
+
+	score, feedback, result := codeScore(question, judgeResponse(statusAccepted, statusAccepted, statusAccepted, "Time Limit Exceeded", statusAccepted))
+	// 小数据子任务 2 + 未分组用例 2，共 4/8
+	assert.Equal(t, 6, score)
+	assert.Equal(t, "Passed 4/5 test cases; Subtask 小数据: Passed; Subtask 大数据: Failed", feedback)
+	if assert.Len(t, result.Subtasks, 2) {
+		assert.Equal(t, SubtaskResult{Group: "小数据", Weight: 2, Cases: 2, Passed: true}, result.Subtasks[0])
+		assert.Equal(t, SubtaskResult{Group: "大数据", Weight: 4, Cases: 2, Passed: false}, result.Subtasks[1])
+	}
+}
+
+func TestSubmitExperiment_SubtaskBreakdown(t *testing.T) {
+	setupTestDBTeacher(t)
+	fakeJudgeStatuses(t, statusAccepted, "Wrong Answer", statusAccepted)
+	stu := createTestUser(t, "student")
+	exp := models.Experiment{
+		ID:         "exp-subtask",
+		Title:      "排序实验",
+		Permission: 1,
+		Deadline:   time.Now().Add(time.Hour),
+		Users:      []models.User{stu},
+		Questions: []models.Question{
+			{ID: "q-code", Type: "code", Content: "实现排序", Score: 10,
+				TestCases: `[{"input":"1","expected_output":"1","group":"A"},{"input":"2","expected_output":"2","group":"A"},{"input":"3","expected_output":"3","group":"B"}]`},
+		},
+	}
+	global.DB.Create(&exp)
+
+	body := `{"answers":[{"question_id":"q-code","type":"code","code":"print(1)","language":"python"}]}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Params = gin.Params{{Key: "experiment_id", Value: exp.ID}}
+	c.Request = httptest.NewRequest("POST", "/submit", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SubmitExperiment(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	// 子任务 A 未全部通过，只获得子任务 B 的 1/3
+	assert.Contains(t, w.Body.String(), `"total_score":"3/10"`)
+	assert.Contains(t, w.Body.String(), "Subtask A: Failed; Subtask B: Passed")
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", stu)
+	c.Request = httptest.NewRequest("GET", "/submissions", nil)
+	GetSubmissions(c)
+	var resp struct {
+		Data []struct {
+			Results []struct {
+				JudgeResult *JudgeResult `json:"judge_result"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if assert.Len(t, resp.Data, 1) && assert.Len(t, resp.Data[0].Results, 1) && assert.NotNil(t, resp.Data[0].Results[0].JudgeResult) {
+		result := resp.Data[0].Results[0].JudgeResult
+		assert.Len(t, result.Cases, 3)
+		assert.False(t, result.Subtasks[0].Passed)
+		assert.True(t, result.Subtasks[1].Passed)
+	}
+}
+
+func TestCodeScore_HiddenCases(t *testing.T) {
+	question := models.Question{Type: "code", Score: 4, TestCases: `[
+		{"input":"1 2","expected_output":"3","sample":true},
+		{"input":"100 200","expected_output":"300"}]`}
+	response := judgeResponse(statusAccepted, "Wrong Answer")
+	response.CaseResults[0].Stdout = "3\n"
+	response.CaseResults[1].Stdout = "299\n"
+
+	_, _, result := codeScore(question, response)
+	if assert.Len(t, result.Cases, 2) {
+		assert.Equal(t, "1 2", result.Cases[0].Input)
+		assert.Equal(t, "3\n", result.Cases[0].Stdout)
+		// 隐藏用例只有评测状态
+		assert.False(t, result.Cases[1].Passed)
+		assert.Nil(t, result.Cases[1].Input)
+		assert.Nil(t, result.Cases[1].ExpectedOutput)
+		assert.Empty(t, result.Cases[1].Stdout)
+	}
+	data, _ := json.Marshal(result)
+	assert.NotContains(t, string(data), "300")
+
+	samples := sampleCases(question)
+	assert.Equal(t, []gin.H{{"input": "1 2", "expected_output": "3"}}, samples)
+}
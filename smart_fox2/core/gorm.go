@@ -80,6 +80,10 @@ func InitGorm() *gorm.DB {
 		&models.User{},
 		&models.Notification{},
 		&models.Group{},
+		&models.Course{},
+		&models.DeadlineExtension{},
+		&models.BankQuestion{},
+		&models.QuestionPool{},
 	)
 
 	return db
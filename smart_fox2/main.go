@@ -5,6 +5,7 @@ import (
 	"lh/core"
 	"lh/global"
 	"lh/routers"
+	"time"
 )
 
 func main() {
@@ -14,6 +15,13 @@ func main() {
 	global.Log = core.InitLogger()
 	//连接数据库
 	global.DB = core.InitGorm()
+	if global.DB != nil {
+		if err := controller.BackfillExperimentOwners(global.DB); err != nil {
+			global.Log.Warnf("补全历史实验的创建者失败: %v", err)
+		}
+		// 定时自动提交已到时的限时作答，学生不再访问时也能按时评分
+		go controller.SweepExpiredAttempts(global.DB, time.Minute)
+	}
 	controller.InitOSS()
 	router := routers.InitRouter()
 
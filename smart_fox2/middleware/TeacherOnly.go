@@ -16,7 +16,8 @@ func TeacherOnly() gin.HandlerFunc {
 			return
 		}
 
-		if user.(models.User).Role != "teacher" {
+		// 管理员可以使用教师的全部功能
+		if role := user.(models.User).Role; role != "teacher" && role != "admin" {
 			ctx.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "仅教师可访问"})
 			ctx.Abort()
 			return
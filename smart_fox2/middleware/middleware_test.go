@@ -308,4 +308,22 @@ func TestTeacherOnly(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Contains(t, w.Body.String(), "success")
 	})
+
+	// 测试用例4: 管理员访问
+	t.Run("管理员访问", func(t *testing.T) {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", createTestUser("admin"))
+		})
+		router.Use(TeacherOnly())
+		router.GET("/test", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "success"})
+		})
+
+		req, _ := http.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Course 课程模型，实验、学生分组和通知都可以归属于某门课程
+type Course struct {
+	ID          string    `json:"course_id" gorm:"primaryKey;type:char(36)"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null"`
+	Code        string    `json:"code" gorm:"type:varchar(50);index"`
+	Term        string    `json:"term" gorm:"type:varchar(50)"` // 学期，如 2025-2026-1
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Teachers    []User    `json:"teachers" gorm:"many2many:course_teachers;foreignKey:ID;joinForeignKey:CourseID;References:ID;JoinReferences:UserID"`
+	Students    []User    `json:"students" gorm:"many2many:course_students;foreignKey:ID;joinForeignKey:CourseID;References:ID;JoinReferences:UserID"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCourseModel(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	t.Run("正向测试: 创建有效课程", func(t *testing.T) {
+		// 模拟数据库期望
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `courses`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		course := Course{
+			ID:        "course-123456",
+			Name:      "操作系统实验",
+			Code:      "CS301",
+			Term:      "2025-2026-1",
+			CreatedAt: time.Now(),
+		}
+
+		result := db.Create(&course)
+		if result.Error != nil {
+			t.Errorf("创建课程失败: %v", result.Error)
+		}
+	})
+
+	t.Run("反向测试: 创建课程缺少必需字段", func(t *testing.T) {
+		course := Course{
+			ID: "course-123456",
+			// 缺少Name字段
+		}
+
+		result := db.Create(&course)
+		if result.Error == nil {
+			t.Error("缺少必需字段时应该报错")
+		}
+	})
+}
@@ -6,34 +6,82 @@ import (
 
 // Experiment 实验模型
 type Experiment struct {
-	ID          string    `json:"experiment_id" gorm:"primaryKey;type:char(36)"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	FileURL     string    `json:"file_url,omitempty"`
-	Permission  int       `json:"permission"`
-	Deadline    time.Time `json:"deadline"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time
-	Questions   []Question   `json:"questions" gorm:"foreignKey:ExperimentID"`
-	Attachments []Attachment `json:"attachments" gorm:"foreignKey:ExperimentID"`
-	Users       []User       `json:"student_ids" gorm:"many2many:experiment_users;foreignKey:ID;joinForeignKey:ExperimentID;References:ID;JoinReferences:UserID"`
+	ID               string     `json:"experiment_id" gorm:"primaryKey;type:char(36)"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	FileURL          string     `json:"file_url,omitempty"`
+	Permission       int        `json:"permission"`
+	CourseID         string     `json:"course_id" gorm:"type:char(36);index"` // 所属课程，可为空
+	OpensAt          *time.Time `json:"opens_at"`                             // 开放时间，为空表示立即开放
+	Deadline         time.Time  `json:"deadline"`
+	DurationMinutes  int        `json:"duration_minutes"`                                      // 限时作答时长（分钟），0 表示不限时
+	MaxAttempts      int        `json:"max_attempts"`                                          // 最多提交次数，0 表示不限
+	ShuffleQuestions bool       `json:"shuffle_questions"`                                     // 按学生打乱题目顺序
+	ShuffleOptions   bool       `json:"shuffle_options"`                                       // 按学生打乱选择题选项
+	ScoringPolicy    string     `json:"scoring_policy" gorm:"type:varchar(10);default:'last'"` // 多次提交的计分策略：best, last, average
+	// 迟交策略，仅在 Permission 为 1（允许迟交）时生效
+	LatePenaltyPercent float64   `json:"late_penalty_percent"`                                    // 每迟交一个单位扣除的百分比
+	LatePenaltyUnit    string    `json:"late_penalty_unit" gorm:"type:varchar(10);default:'day'"` // 扣分单位：hour, day
+	LateGraceMinutes   int       `json:"late_grace_minutes"`                                      // 截止后不扣分的宽限时间
+	LateCutoffHours    int       `json:"late_cutoff_hours"`                                       // 截止后超过该时长不再接受提交，0 表示不限
+	LateMaxPenalty     float64   `json:"late_max_penalty"`                                        // 最高扣分百分比，0 表示不设上限
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time
+	Questions          []Question     `json:"questions" gorm:"foreignKey:ExperimentID"`
+	Attachments        []Attachment   `json:"attachments" gorm:"foreignKey:ExperimentID"`
+	Pools              []QuestionPool `json:"pools" gorm:"foreignKey:ExperimentID"` // 抽题规则，为空表示所有学生作答全部题目
+	Users              []User         `json:"student_ids" gorm:"many2many:experiment_users;foreignKey:ID;joinForeignKey:ExperimentID;References:ID;JoinReferences:UserID"`
+	TeacherID          uint           `json:"teacher_id" gorm:"index"` // 创建实验的教师
+	CoTeachers         []User         `json:"co_teachers" gorm:"many2many:experiment_teachers;foreignKey:ID;joinForeignKey:ExperimentID;References:ID;JoinReferences:UserID"`
+	Groups             []Group        `json:"groups" gorm:"many2many:experiment_groups;foreignKey:ID;joinForeignKey:ExperimentID;References:ID;JoinReferences:GroupID"` // 按分组分配，成员动态解析
 }
 
 // Question 题目模型
 type Question struct {
-	ID            string `json:"id" gorm:"primaryKey;type:char(36)"`
-	ExperimentID  string `json:"experiment_id"`
-	Type          string `json:"type"` // choice, blank, code
-	Content       string `json:"content"`
-	Options       string `json:"options,omitempty" gorm:"type:text"` // JSON 字符串存储选择题选项
-	CorrectAnswer string `json:"correct_answer,omitempty"`
-	Score         int    `json:"score"`
+	ID            string  `json:"id" gorm:"primaryKey;type:char(36)"`
+	ExperimentID  string  `json:"experiment_id"`
+	Type          string  `json:"type"` // choice, multi_choice, blank, numeric, true_false, code, essay
+	Content       string  `json:"content"`
+	Options       string  `json:"options,omitempty" gorm:"type:text"` // JSON 字符串存储选择题选项
+	CorrectAnswer string  `json:"correct_answer,omitempty"`           // 多选题以 JSON 数组存储全部正确选项
+	Score         int     `json:"score"`
+	ScoringMode   string  `json:"scoring_mode,omitempty" gorm:"type:varchar(20)"`   // 多选题计分方式：all_or_nothing（默认）、proportional、penalty
+	Tolerance     float64 `json:"tolerance,omitempty"`                              // 数值题允许的误差，相对误差按比例填写，如 0.05 表示 5%
+	ToleranceType string  `json:"tolerance_type,omitempty" gorm:"type:varchar(10)"` // absolute（默认）或 relative
+	Unit          string  `json:"unit,omitempty" gorm:"type:varchar(20)"`           // 数值题单位，作答时带不带单位均可
+	BlankConfig   string  `json:"blank_config,omitempty" gorm:"type:text"`          // JSON 字符串存储填空题的可接受答案和规范化选项
+	Rubric        string  `json:"rubric,omitempty" gorm:"type:text"`                // JSON 字符串存储代码题和主观题的评分细则
 
 	ImageURL    string `json:"image_url,omitempty"`
 	TestCases   string `json:"test_cases,omitempty"` // JSON 字符串存储代码题的测试用例
 	Explanation string `json:"explanation,omitempty"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+
+	TimeLimit         int     `json:"time_limit,omitempty"`                           // 代码题时间限制（秒），0 表示默认 2 秒
+	MemoryLimitMB     int     `json:"memory_limit_mb,omitempty"`                      // 代码题内存限制（MB），0 表示使用评测服务默认值
+	LanguageLimits    string  `json:"language_limits,omitempty" gorm:"type:text"`     // JSON 字符串存储按语言覆盖的时间和内存限制
+	CompareMode       string  `json:"compare_mode,omitempty" gorm:"type:varchar(20)"` // 代码题输出比较方式：exact（默认）、ignore_whitespace、float、checker
+	FloatEpsilon      float64 `json:"float_epsilon,omitempty"`                        // float 比较方式允许的误差，0 表示评测服务默认值
+	Checker           string  `json:"checker,omitempty" gorm:"type:text"`             // JSON 字符串存储检查程序的语言和源代码
+	StarterCode       string  `json:"starter_code,omitempty" gorm:"type:text"`        // JSON 字符串按语言存储起始代码，学生尚未作答时返回
+	Harness           string  `json:"harness,omitempty" gorm:"type:text"`             // JSON 字符串按语言存储函数题的测试框架，不向学生展示
+	ReferenceSolution string  `json:"reference_solution,omitempty" gorm:"type:text"`  // JSON 字符串存储参考答案的语言和源代码，不向学生展示
+
+	BankQuestionID string `json:"bank_question_id,omitempty" gorm:"type:char(36);index"` // 复制来源的题库题目，仅作溯源
+	Pool           string `json:"pool,omitempty" gorm:"type:varchar(50)"`                // 所属题目池，为空表示必答题
+	Difficulty     string `json:"difficulty,omitempty" gorm:"type:varchar(10)"`          // easy, medium, hard
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// QuestionPool 抽题规则：从题目池中为每个学生随机抽取若干道题，可按难度限定
+type QuestionPool struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	ExperimentID string `json:"experiment_id" gorm:"type:char(36);index"`
+	Pool         string `json:"pool" gorm:"type:varchar(50);not null"`
+	Difficulty   string `json:"difficulty,omitempty" gorm:"type:varchar(10)"` // 为空表示不限难度
+	DrawCount    int    `json:"draw_count"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
 
 // Attachment 附件模型
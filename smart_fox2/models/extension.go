@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DeadlineExtension 学生个人的截止时间延长和限时作答时长倍数，不影响实验对其他学生的设置
+type DeadlineExtension struct {
+	ID             uint       `json:"extension_id" gorm:"primaryKey"`
+	ExperimentID   string     `json:"experiment_id" gorm:"type:char(36);not null;uniqueIndex:idx_extension_student"`
+	StudentID      uint       `json:"student_id" gorm:"not null;uniqueIndex:idx_extension_student"`
+	Deadline       *time.Time `json:"deadline"`                         // 个人截止时间，为空表示沿用实验截止时间
+	TimeMultiplier float64    `json:"time_multiplier" gorm:"default:1"` // 限时作答时长倍数，如 1.5
+	Reason         string     `json:"reason" gorm:"type:varchar(255)"`  // 延期原因，如病假、无障碍需求
+	GrantedBy      uint       `json:"granted_by"`                       // 授予延期的教师
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDeadlineExtensionModel(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	t.Run("正向测试: 创建延期记录", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `deadline_extensions`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		deadline := time.Now().Add(48 * time.Hour)
+		extension := DeadlineExtension{
+			ExperimentID:   "exp-123456",
+			StudentID:      1,
+			Deadline:       &deadline,
+			TimeMultiplier: 1.5,
+			Reason:         "病假",
+			GrantedBy:      2,
+		}
+
+		result := db.Create(&extension)
+		if result.Error != nil {
+			t.Errorf("创建延期记录失败: %v", result.Error)
+		}
+	})
+
+	t.Run("反向测试: 数据库写入失败", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `deadline_extensions`").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		extension := DeadlineExtension{ExperimentID: "exp-123456", StudentID: 1}
+		result := db.Create(&extension)
+		if result.Error == nil {
+			t.Error("数据库写入失败时应该报错")
+		}
+	})
+}
@@ -6,11 +6,14 @@ type Notification struct {
 	ID           string    `json:"id" gorm:"primaryKey"`
 	Title        string    `json:"title"`
 	Content      string    `json:"content"`
-	ExperimentID string    `json:"experiment_id"` // 可选关联
+	ExperimentID string    `json:"experiment_id"`                        // 可选关联
+	CourseID     string    `json:"course_id" gorm:"type:char(36);index"` // 可选关联
 	CreatedAt    time.Time `json:"created_at"`
-	IsImportant  bool      `json:"is_important"` // 是否为公告（高亮）
+	IsImportant  bool      `json:"is_important"`            // 是否为公告（高亮）
+	TeacherID    uint      `json:"teacher_id" gorm:"index"` // 发布通知的教师
 	//Users        []string  `json:"users" gorm:"type:json"`
-	Users []User `gorm:"many2many:notification_users"`
+	Users  []User  `gorm:"many2many:notification_users"`
+	Groups []Group `gorm:"many2many:notification_groups;foreignKey:ID;joinForeignKey:NotificationID;References:ID;JoinReferences:GroupID"` // 分组成员动态接收
 }
 
 // 学生是否已读公告
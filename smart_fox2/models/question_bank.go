@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// BankQuestion 教师题库中的题目，组卷时复制到实验中，之后修改题库不会影响已有实验
+type BankQuestion struct {
+	ID            string  `json:"bank_question_id" gorm:"primaryKey;type:char(36)"`
+	TeacherID     uint    `json:"teacher_id" gorm:"index"`
+	Type          string  `json:"type" gorm:"type:varchar(20);index"` // choice, multi_choice, blank, numeric, true_false, code, essay
+	Content       string  `json:"content" gorm:"type:text;not null"`
+	Options       string  `json:"options,omitempty" gorm:"type:text"` // JSON 字符串存储选择题选项
+	CorrectAnswer string  `json:"correct_answer,omitempty"`
+	Score         int     `json:"score"`
+	ScoringMode   string  `json:"scoring_mode,omitempty" gorm:"type:varchar(20)"`   // 多选题计分方式
+	Tolerance     float64 `json:"tolerance,omitempty"`                              // 数值题允许的误差
+	ToleranceType string  `json:"tolerance_type,omitempty" gorm:"type:varchar(10)"` // absolute 或 relative
+	Unit          string  `json:"unit,omitempty" gorm:"type:varchar(20)"`           // 数值题单位
+	BlankConfig   string  `json:"blank_config,omitempty" gorm:"type:text"`          // JSON 字符串存储填空题判分配置
+	Rubric        string  `json:"rubric,omitempty" gorm:"type:text"`                // JSON 字符串存储评分细则
+	ImageURL      string  `json:"image_url,omitempty"`
+	TestCases     string  `json:"test_cases,omitempty" gorm:"type:text"` // JSON 字符串存储代码题的测试用例
+	Explanation   string  `json:"explanation,omitempty"`
+
+	// 代码题的评测设置，含义与 Question 相同，组卷时一并复制
+	TimeLimit         int     `json:"time_limit,omitempty"`
+	MemoryLimitMB     int     `json:"memory_limit_mb,omitempty"`
+	LanguageLimits    string  `json:"language_limits,omitempty" gorm:"type:text"`
+	CompareMode       string  `json:"compare_mode,omitempty" gorm:"type:varchar(20)"`
+	FloatEpsilon      float64 `json:"float_epsilon,omitempty"`
+	Checker           string  `json:"checker,omitempty" gorm:"type:text"`
+	StarterCode       string  `json:"starter_code,omitempty" gorm:"type:text"`
+	Harness           string  `json:"harness,omitempty" gorm:"type:text"`
+	ReferenceSolution string  `json:"reference_solution,omitempty" gorm:"type:text"`
+
+	Difficulty string    `json:"difficulty" gorm:"type:varchar(10);index"` // easy, medium, hard
+	Tags       string    `json:"tags" gorm:"type:text"`                    // JSON 数组存储标签
+	Topics     string    `json:"topics" gorm:"type:text"`                  // JSON 数组存储知识点
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
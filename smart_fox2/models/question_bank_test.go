@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBankQuestionModel(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	t.Run("正向测试: 创建题库题目", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `bank_questions`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		question := BankQuestion{
+			ID:            "bq-123456",
+			TeacherID:     1,
+			Type:          "choice",
+			Content:       "进程和线程的区别？",
+			Options:       `["A","B"]`,
+			CorrectAnswer: "A",
+			Score:         5,
+			Difficulty:    "easy",
+			Tags:          `["操作系统"]`,
+			CreatedAt:     time.Now(),
+		}
+
+		result := db.Create(&question)
+		if result.Error != nil {
+			t.Errorf("创建题库题目失败: %v", result.Error)
+		}
+	})
+
+	t.Run("反向测试: 数据库写入失败", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `bank_questions`").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		question := BankQuestion{ID: "bq-654321", Type: "blank", Content: "1+1="}
+		result := db.Create(&question)
+		if result.Error == nil {
+			t.Error("数据库写入失败时应该报错")
+		}
+	})
+}
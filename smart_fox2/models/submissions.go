@@ -11,11 +11,18 @@ type ExperimentSubmission struct {
 	StudentID uint `json:"student_id" gorm:"index"`
 	Student   User `json:"student" gorm:"foreignKey:StudentID"`
 
-	SubmittedAt time.Time `json:"submitted_at"`
-	TotalScore  int       `json:"total_score"`
-	Status      string    `json:"status" gorm:"type:varchar(20);"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	SubmittedAt    time.Time  `json:"submitted_at"`
+	StartedAt      *time.Time `json:"started_at"`   // 限时实验开始作答时间
+	EndsAt         *time.Time `json:"ends_at"`      // 限时实验个人截止时间
+	RawScore       int        `json:"raw_score"`    // 迟交扣分前的得分
+	LatePenalty    int        `json:"late_penalty"` // 迟交扣除的分数
+	TotalScore     int        `json:"total_score"`
+	Status         string     `json:"status" gorm:"type:varchar(20);"` // in_progress, submitted, pending_review（有主观题待批阅）, graded（主观题已批阅）
+	Attempt        int        `json:"attempt_number"`                  // 第几次提交，草稿为 0
+	ShuffleSeed    int64      `json:"-"`                               // 打乱题目和选项顺序的种子，同一学生各次作答相同
+	DrawnQuestions string     `json:"-" gorm:"type:text"`              // JSON 数组存储从题目池抽到的题目 ID，为空表示作答全部题目
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // QuestionSubmission 模型
@@ -25,15 +32,26 @@ type QuestionSubmission struct {
 	SubmissionID         string               `json:"submission_id" gorm:"type:char(36);index"`
 	ExperimentSubmission ExperimentSubmission `json:"experiment_submission" gorm:"foreignKey:SubmissionID"`
 
-	QuestionID   string    `json:"question_id" gorm:"type:char(36);index"`
-	Question     Question  `json:"question" gorm:"foreignKey:QuestionID"`
-	Type         string    `json:"type" gorm:"type:text"`
-	PerfectScore int       `json:"PerfectScore" gorm:"default:0"`
-	Answer       string    `json:"answer" gorm:"type:text"`
-	Code         string    `json:"code" gorm:"type:text"`
-	Language     string    `json:"Language" gorm:"type:text"` //only for code
-	Score        int       `json:"score" gorm:"default:0"`
-	Feedback     string    `json:"feedback" gorm:"type:text"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	QuestionID     string     `json:"question_id" gorm:"type:char(36);index"`
+	Question       Question   `json:"question" gorm:"foreignKey:QuestionID"`
+	Type           string     `json:"type" gorm:"type:text"`
+	PerfectScore   int        `json:"PerfectScore" gorm:"default:0"`
+	Answer         string     `json:"answer" gorm:"type:text"`
+	Code           string     `json:"code" gorm:"type:text"`
+	Language       string     `json:"Language" gorm:"type:text"` //only for code
+	Score          int        `json:"score" gorm:"default:0"`
+	Feedback       string     `json:"feedback" gorm:"type:text"`
+	ReviewStatus   string     `json:"review_status,omitempty" gorm:"type:varchar(20);index"` // 需人工批阅的题目：pending 或 graded，自动评分的题目为空
+	GradedBy       *uint      `json:"graded_by,omitempty"`
+	GradedAt       *time.Time `json:"graded_at,omitempty"`
+	AutoScore      *int       `json:"auto_score,omitempty"`                       // 教师改分前的自动评分，保留用于审计
+	OverrideReason string     `json:"override_reason,omitempty" gorm:"type:text"` // 改分原因
+	Comment        string     `json:"comment,omitempty" gorm:"type:text"`         // 教师评语，学生可见
+	OverriddenBy   *uint      `json:"overridden_by,omitempty"`
+	OverriddenAt   *time.Time `json:"overridden_at,omitempty"`
+	RubricScore    int        `json:"rubric_score" gorm:"default:0"`            // 按评分细则批阅得到的分数，已计入 Score
+	RubricGrades   string     `json:"rubric_grades,omitempty" gorm:"type:text"` // JSON 字符串存储各项评分标准的批阅结果
+	JudgeResult    string     `json:"judge_result,omitempty" gorm:"type:text"`  // JSON 字符串存储代码题各测试用例和子任务的评测结果
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
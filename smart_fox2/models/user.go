@@ -16,6 +16,7 @@ type User struct {
 
 type Group struct {
 	gorm.Model
-	Name    string `gorm:"varchar(20);not null"`
-	Student []User `gorm:"many2many:group_students;foreignKey:ID;joinForeignKey:GroupID;References:ID;JoinReferences:UserID"`
+	Name     string `gorm:"varchar(20);not null"`
+	CourseID string `gorm:"type:char(36);index"` // 所属课程，可为空
+	Student  []User `gorm:"many2many:group_students;foreignKey:ID;joinForeignKey:GroupID;References:ID;JoinReferences:UserID"`
 }
@@ -149,10 +149,33 @@ func TestExperimentRoutes_Teacher(t *testing.T) {
 			{"PUT", "/api/teacher/experiments/:experiment_id"},
 			{"DELETE", "/api/teacher/experiments/:experiment_id"},
 			{"GET", "/api/teacher/experiments/:experiment_id/:student_id/submissions"},
+			{"GET", "/api/teacher/experiments/:experiment_id/extensions"},
+			{"PUT", "/api/teacher/experiments/:experiment_id/extensions/:student_id"},
+			{"DELETE", "/api/teacher/experiments/:experiment_id/extensions/:student_id"},
+			{"GET", "/api/teacher/experiments/:experiment_id/reviews"},
+			{"PUT", "/api/teacher/experiments/:experiment_id/reviews/:answer_id"},
+			{"PUT", "/api/teacher/experiments/:experiment_id/reviews/:answer_id/rubric"},
+			{"PUT", "/api/teacher/experiments/:experiment_id/answers/:answer_id/score"},
+			{"POST", "/api/teacher/experiments/:experiment_id/questions/import"},
+			{"POST", "/api/teacher/experiments/:experiment_id/questions/:question_id/expected-outputs"},
+			{"GET", "/api/teacher/experiments/:experiment_id/export"},
+			{"POST", "/api/teacher/experiments/import"},
 			{"POST", "/api/teacher/experiments/:experiment_id/uploadFile"},
 			{"POST", "/api/teacher/experiments/notifications"},
 			{"GET", "/api/teacher/experiments/notifications"},
 			{"DELETE", "/api/teacher/experiments/:experiment_id/files/:filename"},
+			{"POST", "/api/teacher/courses"},
+			{"GET", "/api/teacher/courses"},
+			{"GET", "/api/teacher/courses/:course_id"},
+			{"PUT", "/api/teacher/courses/:course_id"},
+			{"DELETE", "/api/teacher/courses/:course_id"},
+			{"POST", "/api/teacher/courses/:course_id/students"},
+			{"DELETE", "/api/teacher/courses/:course_id/students/:student_id"},
+			{"POST", "/api/teacher/question-bank"},
+			{"GET", "/api/teacher/question-bank"},
+			{"GET", "/api/teacher/question-bank/:question_id"},
+			{"PUT", "/api/teacher/question-bank/:question_id"},
+			{"DELETE", "/api/teacher/question-bank/:question_id"},
 		}
 
 		for _, route := range teacherRoutes {
@@ -186,6 +209,7 @@ func TestExperimentRoutes_Student(t *testing.T) {
 			{"POST", "/api/student/experiments/:experiment_id/submit"},
 			{"GET", "/api/student/submissions"},
 			{"GET", "/api/student/experiments/notifications/:student_id"},
+			{"GET", "/api/student/courses"},
 		}
 
 		for _, route := range studentRoutes {
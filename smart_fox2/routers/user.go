@@ -45,10 +45,33 @@ func ExperimentRoutes_Teacher(r *gin.RouterGroup) {
 	r.PUT("/experiments/:experiment_id", controller.UpdateExperiment)
 	r.DELETE("/experiments/:experiment_id", controller.DeleteExperiment)
 	r.GET("/experiments/:experiment_id/:student_id/submissions", controller.GetStudentSubmissions)
+	r.GET("/experiments/:experiment_id/extensions", controller.GetExtensions)
+	r.PUT("/experiments/:experiment_id/extensions/:student_id", controller.SetExtension)
+	r.DELETE("/experiments/:experiment_id/extensions/:student_id", controller.DeleteExtension)
+	r.GET("/experiments/:experiment_id/reviews", controller.GetReviewAnswers)
+	r.PUT("/experiments/:experiment_id/reviews/:answer_id", controller.GradeAnswer)
+	r.PUT("/experiments/:experiment_id/reviews/:answer_id/rubric", controller.GradeRubric)
+	r.PUT("/experiments/:experiment_id/answers/:answer_id/score", controller.OverrideAnswerScore)
+	r.POST("/experiments/:experiment_id/questions/import", controller.ImportQuestions)
+	r.POST("/experiments/:experiment_id/questions/:question_id/expected-outputs", controller.RegenerateExpectedOutputs)
+	r.GET("/experiments/:experiment_id/export", controller.ExportExperiment)
+	r.POST("/experiments/import", controller.ImportExperiment)
 	r.POST("/experiments/:experiment_id/uploadFile", controller.HandleTeacherUpload)
 	r.POST("/experiments/notifications", controller.CreateNotification)
 	r.GET("/experiments/notifications", controller.GetTeacherNotifications)
 	r.DELETE("/experiments/:experiment_id/files/:filename", controller.TeacherDeleteFile)
+	r.POST("/courses", controller.CreateCourse)
+	r.GET("/courses", controller.GetCourses_Teacher)
+	r.GET("/courses/:course_id", controller.GetCourseDetail_Teacher)
+	r.PUT("/courses/:course_id", controller.UpdateCourse)
+	r.DELETE("/courses/:course_id", controller.DeleteCourse)
+	r.POST("/courses/:course_id/students", controller.AddCourseStudents)
+	r.DELETE("/courses/:course_id/students/:student_id", controller.RemoveCourseStudent)
+	r.POST("/question-bank", controller.CreateBankQuestion)
+	r.GET("/question-bank", controller.GetBankQuestions)
+	r.GET("/question-bank/:question_id", controller.GetBankQuestionDetail)
+	r.PUT("/question-bank/:question_id", controller.UpdateBankQuestion)
+	r.DELETE("/question-bank/:question_id", controller.DeleteBankQuestion)
 }
 
 func ExperimentRoutes_Student(r *gin.RouterGroup) {
@@ -59,6 +82,7 @@ func ExperimentRoutes_Student(r *gin.RouterGroup) {
 	r.POST("/experiments/:experiment_id/save", controller.SaveAnswer)
 	r.POST("/experiments/:experiment_id/submit", controller.SubmitExperiment)
 	r.GET("/submissions", controller.GetSubmissions)
+	r.GET("/courses", controller.GetCourses_Student)
 
 	r.GET("/experiments/notifications/:student_id", controller.GetStudentNotifications)
 }